@@ -19,9 +19,13 @@ package gengo
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +33,8 @@ import (
 	"k8s.io/gengo/v2/generator"
 	"k8s.io/gengo/v2/namer"
 	"k8s.io/gengo/v2/parser"
+	"k8s.io/gengo/v2/types"
+	"k8s.io/klog/v2"
 )
 
 // StdBuildTag is a suggested build-tag which tools can use both as an argument
@@ -62,37 +68,372 @@ func GoBoilerplate(headerFile, buildTag, generatedBy string) ([]byte, error) {
 	}
 
 	if generatedBy != "" {
-		generatorName := filepath.Base(os.Args[0])
-		// Strip the extension from the name to normalize output between *nix and Windows.
-		generatorName = generatorName[:len(generatorName)-len(filepath.Ext(generatorName))]
-		generatedByComment := strings.ReplaceAll(generatedBy, "GENERATOR_NAME", generatorName)
-		buf.WriteString(fmt.Sprintf("%s\n\n", generatedByComment))
+		buf.WriteString(fmt.Sprintf("%s\n\n", GeneratedByLine(generatedBy)))
 	}
 
 	return buf.Bytes(), nil
 }
 
+// GeneratedByLine returns the "generated by" comment which GoBoilerplate
+// emits for generatedBy (e.g. StdGeneratedBy), with GENERATOR_NAME replaced by
+// the name of the running tool.
+func GeneratedByLine(generatedBy string) string {
+	generatorName := filepath.Base(os.Args[0])
+	// Strip the extension from the name to normalize output between *nix and Windows.
+	generatorName = generatorName[:len(generatorName)-len(filepath.Ext(generatorName))]
+	return strings.ReplaceAll(generatedBy, "GENERATOR_NAME", generatorName)
+}
+
+// Options holds optional settings for ExecuteWithOptions.
+type Options struct {
+	// BuildTags is a list of optional tags to be specified when loading
+	// packages.
+	BuildTags []string
+
+	// IncludeTestFiles, if true, causes the requested packages to be loaded
+	// along with their _test.go files. See parser.Options.IncludeTestFiles.
+	IncludeTestFiles bool
+
+	// Overlay maps absolute file paths to contents which are parsed in
+	// place of those files' contents on disk, e.g. to run a generator
+	// against unsaved edits. See parser.Options.Overlay.
+	Overlay map[string][]byte
+
+	// Dir and Env are the directory and environment in which to run the go
+	// command, which finds and loads packages, e.g. to load them in a
+	// go.work workspace. See parser.Options.Dir and parser.Options.Env.
+	Dir string
+	Env []string
+
+	// ParseCacheDir, if not empty, is a directory in which to cache the
+	// parsed packages, which can be shared by tools which are run on the same
+	// packages one after another. See parser.Options.CacheDir.
+	ParseCacheDir string
+
+	// Verify, if true, causes the generated output to be compared against
+	// what is already on disk, rather than written. Any stale, missing, or
+	// extra files are reported as a *generator.VerifyError.
+	Verify bool
+
+	// DiffOutput, if not nil, implies Verify. If any files are out of date, a
+	// unified diff (which would bring them up to date) is written here, e.g.
+	// os.Stdout or a patch file.
+	DiffOutput io.Writer
+
+	// OutputFS, if not nil, is the filesystem to which output is written
+	// (and, when verifying, from which it is read). If nil, the real
+	// filesystem is used.
+	OutputFS generator.OutputFS
+
+	// Parallelism is the maximum number of targets to execute at once. See
+	// generator.Context.Parallelism for what this requires of generators.
+	Parallelism int
+
+	// Cleanup, if true, removes files which this tool generated previously
+	// (see GeneratedBy), but which were not generated in this run, from
+	// the targets' directories. See generator.Context.Cleanup.
+	Cleanup bool
+
+	// GeneratedBy is the "generated by" line passed to GoBoilerplate (e.g.
+	// StdGeneratedBy), which identifies files owned by this tool. It is
+	// used to find orphaned files for Cleanup and Verify. If empty, no
+	// files are recognized, and Cleanup removes nothing.
+	GeneratedBy string
+
+	// OnFormatFailure says what to do with Go files which can't be
+	// formatted. The default is to write them unformatted.
+	OnFormatFailure generator.FormatFailurePolicy
+
+	// ManifestFile, if not empty, is where to write a JSON manifest which
+	// describes every file that was generated (see generator.Manifest). It
+	// is not written in verify mode.
+	ManifestFile string
+
+	// CacheFile, if not empty, enables incremental generation: targets
+	// whose inputs have not changed since the last run, and whose output is
+	// still in place, are skipped. The state needed for this is kept in the
+	// named file. See generator.ExecutionCache for what counts as an input.
+	CacheFile string
+
+	// CacheVersion is the version of the tool, which is included in the
+	// cache's identity along with the tool's name and arguments, the build
+	// tags and the version of gengo. Changing it invalidates the cache.
+	CacheVersion string
+}
+
 // Execute implements most of a tool's main loop.
 func Execute(nameSystems namer.NameSystems, defaultSystem string, getTargets func(*generator.Context) []generator.Target, buildTag string, patterns []string) error {
 	var buildTags []string
 	if buildTag != "" {
 		buildTags = append(buildTags, buildTag)
 	}
+	return ExecuteWithOptions(nameSystems, defaultSystem, getTargets, Options{BuildTags: buildTags}, patterns)
+}
 
-	p := parser.NewWithOptions(parser.Options{BuildTags: buildTags})
-	if err := p.LoadPackages(patterns...); err != nil {
-		return fmt.Errorf("failed making a parser: %v", err)
+// ExecuteWithOptions is like Execute, but accepts optional settings.
+func ExecuteWithOptions(nameSystems namer.NameSystems, defaultSystem string, getTargets func(*generator.Context) []generator.Target, opts Options, patterns []string) error {
+	p, err := loadPackages(opts, patterns)
+	if err != nil {
+		return err
 	}
 
 	c, err := generator.NewContext(p, nameSystems, defaultSystem)
 	if err != nil {
 		return fmt.Errorf("failed making a context: %v", err)
 	}
+	if opts.ManifestFile != "" && !opts.Verify && opts.DiffOutput == nil {
+		c.Manifest = generator.NewManifest()
+	}
+
+	err = execute(c, getTargets, opts, opts.GeneratedBy, opts.CacheFile, cacheIdentity(opts))
+	// Save this even if some targets failed, so it describes the targets
+	// which succeeded.
+	if c.Manifest != nil {
+		if err := writeManifest(c.Manifest, opts.ManifestFile); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// loadPackages makes a parser and loads patterns with it.
+func loadPackages(opts Options, patterns []string) (*parser.Parser, error) {
+	p := parser.NewWithOptions(parser.Options{
+		BuildTags:        opts.BuildTags,
+		IncludeTestFiles: opts.IncludeTestFiles,
+		Overlay:          opts.Overlay,
+		Dir:              opts.Dir,
+		Env:              opts.Env,
+		CacheDir:         opts.ParseCacheDir,
+	})
+	if err := p.LoadPackages(patterns...); err != nil {
+		return nil, fmt.Errorf("failed making a parser: %v", err)
+	}
+	return p, nil
+}
+
+// execute executes the targets which getTargets returns for c, as set up by
+// opts. Its Manifest, if any, is left to the caller.
+func execute(c *generator.Context, getTargets func(*generator.Context) []generator.Target, opts Options, generatedBy, cacheFile, identity string) error {
+	c.Verify = opts.Verify || opts.DiffOutput != nil
+	c.OutputFS = opts.OutputFS
+	c.Parallelism = opts.Parallelism
+	c.Cleanup = opts.Cleanup
+	if ft, ok := c.FileTypes[generator.GoFileType].(*generator.DefaultFileType); ok {
+		ft.OnFormatFailure = opts.OnFormatFailure
+	}
+	if generatedBy != "" {
+		c.GeneratedBy = GeneratedByLine(generatedBy)
+	}
+
+	if cacheFile != "" {
+		var err error
+		c.Cache, err = generator.LoadExecutionCache(cacheFile, identity)
+		if err != nil {
+			return fmt.Errorf("failed loading cache: %w", err)
+		}
+		c.Cache.Overlay = opts.Overlay
+	}
 
 	targets := getTargets(c)
-	if err := c.ExecuteTargets(targets); err != nil {
-		return fmt.Errorf("failed executing generator: %v", err)
+	err := c.ExecuteTargets(targets)
+	// Save this even if some targets failed, so it describes the targets
+	// which succeeded.
+	if c.Cache != nil {
+		if err := c.Cache.Save(cacheFile); err != nil {
+			return fmt.Errorf("failed saving cache: %w", err)
+		}
+	}
+	if err != nil {
+		var verifyErr *generator.VerifyError
+		if opts.DiffOutput != nil && errors.As(err, &verifyErr) {
+			if err := verifyErr.WriteDiff(opts.DiffOutput); err != nil {
+				return fmt.Errorf("failed writing diff: %w", err)
+			}
+		}
+		return fmt.Errorf("failed executing generator: %w", err)
 	}
 
 	return nil
 }
+
+// writeManifest writes m as JSON to path.
+func writeManifest(m *generator.Manifest, path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding manifest: %w", err)
+	}
+	if err := (generator.OSFileSystem{}).WriteFile(path, append(b, '\n')); err != nil {
+		return fmt.Errorf("failed writing manifest: %w", err)
+	}
+	return nil
+}
+
+// Plugin is one of several generators which ExecutePlugins runs against one
+// shared parse. Each is executed as if it were a tool of its own which called
+// ExecuteWithOptions.
+type Plugin struct {
+	// Name identifies the plugin, e.g. in errors. Each plugin's name must
+	// be unique.
+	Name string
+
+	// NameSystems and DefaultSystem are the plugin's naming systems, and
+	// the one which its Context's Order is made with.
+	NameSystems   namer.NameSystems
+	DefaultSystem string
+
+	// GetTargets returns the targets to execute for the plugin's Context.
+	GetTargets func(*generator.Context) []generator.Target
+
+	// Patterns are the packages which the plugin generates from, which are
+	// its Context's Inputs.
+	Patterns []string
+
+	// GeneratedBy, if not empty, is used instead of Options.GeneratedBy.
+	GeneratedBy string
+}
+
+// PluginError is an error which a plugin caused.
+type PluginError struct {
+	// The name of the plugin.
+	Plugin string
+	Err    error
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("plugin %s: %v", e.Plugin, e.Err)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// ExecutePlugins loads the packages which all of the plugins generate from
+// once, and executes each plugin in turn, with a Context of its own which
+// shares the parsed Universe. A plugin which fails does not stop the others
+// from being executed. The errors of all of the plugins which failed are
+// returned together, each as a *PluginError.
+//
+// The options apply to every plugin. If there is a CacheFile, each plugin
+// keeps its cache in a file of its own, named by appending "." and the
+// plugin's name. The ManifestFile describes the files of every plugin. With
+// Cleanup, orphaned files are removed once every plugin has been executed,
+// and only if no plugin generated them, so plugins can share directories;
+// in verify mode, such files are reported by an error of their own.
+func ExecutePlugins(plugins []Plugin, opts Options) error {
+	var patterns []string
+	names := map[string]bool{}
+	for _, plugin := range plugins {
+		if names[plugin.Name] {
+			return fmt.Errorf("duplicate plugin name %q", plugin.Name)
+		}
+		names[plugin.Name] = true
+		patterns = append(patterns, plugin.Patterns...)
+	}
+	p, err := loadPackages(opts, patterns)
+	if err != nil {
+		return err
+	}
+	u, err := p.NewUniverse()
+	if err != nil {
+		return fmt.Errorf("failed making a universe: %v", err)
+	}
+	var manifest *generator.Manifest
+	if opts.ManifestFile != "" && !opts.Verify && opts.DiffOutput == nil {
+		manifest = generator.NewManifest()
+	}
+
+	// Each plugin's files would look orphaned to the others, so orphans
+	// are only handled once every plugin has been executed.
+	var orphans *generator.OrphanSet
+	if opts.Cleanup || opts.Verify || opts.DiffOutput != nil {
+		orphans = generator.NewOrphanSet()
+	}
+
+	var errs []error
+	for _, plugin := range plugins {
+		err := executePlugin(p, u, plugin, manifest, orphans, opts)
+		if err != nil {
+			errs = append(errs, &PluginError{Plugin: plugin.Name, Err: err})
+		}
+	}
+	if orphans != nil {
+		if err := handleOrphans(orphans.Orphans(), opts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if manifest != nil {
+		if err := writeManifest(manifest, opts.ManifestFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// executePlugin executes one of the plugins for ExecutePlugins.
+func executePlugin(p *parser.Parser, u types.Universe, plugin Plugin, manifest *generator.Manifest, orphans *generator.OrphanSet, opts Options) error {
+	inputs, err := p.FindPackages(plugin.Patterns...)
+	if err != nil {
+		return fmt.Errorf("failed finding packages: %w", err)
+	}
+	// External test packages are not found by their own paths.
+	if opts.IncludeTestFiles {
+		for _, pkgPath := range inputs {
+			if slices.Contains(p.UserRequestedPackages(), pkgPath+"_test") {
+				inputs = append(inputs, pkgPath+"_test")
+			}
+		}
+	}
+	slices.Sort(inputs)
+	inputs = slices.Compact(inputs)
+
+	c := generator.NewContextFromUniverse(p, u, inputs, plugin.NameSystems, plugin.DefaultSystem)
+	c.Manifest = manifest
+	c.Orphans = orphans
+	generatedBy := opts.GeneratedBy
+	if plugin.GeneratedBy != "" {
+		generatedBy = plugin.GeneratedBy
+	}
+	cacheFile := ""
+	if opts.CacheFile != "" {
+		cacheFile = opts.CacheFile + "." + plugin.Name
+	}
+	identity := cacheIdentity(opts) + fmt.Sprintf(" plugin=%q generatedBy=%q", plugin.Name, generatedBy)
+	return execute(c, plugin.GetTargets, opts, generatedBy, cacheFile, identity)
+}
+
+// handleOrphans removes the orphaned files which ExecutePlugins found, or in
+// verify mode, reports them.
+func handleOrphans(orphans []*generator.MismatchError, opts Options) error {
+	if len(orphans) == 0 {
+		return nil
+	}
+	if opts.Verify || opts.DiffOutput != nil {
+		verifyErr := &generator.VerifyError{Mismatches: orphans}
+		if opts.DiffOutput != nil {
+			if err := verifyErr.WriteDiff(opts.DiffOutput); err != nil {
+				return fmt.Errorf("failed writing diff: %w", err)
+			}
+		}
+		return verifyErr
+	}
+	var fsys generator.OutputFS = generator.OSFileSystem{}
+	if opts.OutputFS != nil {
+		fsys = opts.OutputFS
+	}
+	var errs []error
+	for _, o := range orphans {
+		klog.V(2).Infof("Removing orphaned file %q", o.Path)
+		if err := fsys.Remove(o.Path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cacheIdentity describes everything about this run, other than the inputs,
+// which might affect the output.
+func cacheIdentity(opts Options) string {
+	return fmt.Sprintf("tool=%q version=%q args=%q tags=%q tests=%t gengo=%q",
+		GeneratedByLine("GENERATOR_NAME"), opts.CacheVersion, os.Args[1:], opts.BuildTags, opts.IncludeTestFiles, generator.GengoVersion())
+}
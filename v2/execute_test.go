@@ -0,0 +1,179 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gengo
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/gengo/v2/generator"
+	"k8s.io/gengo/v2/namer"
+	"k8s.io/gengo/v2/types"
+)
+
+// failingGenerator fails to initialize.
+type failingGenerator struct {
+	generator.GoGenerator
+}
+
+func (failingGenerator) Init(*generator.Context, io.Writer) error {
+	return errors.New("failed on purpose")
+}
+
+func TestExecutePlugins(t *testing.T) {
+	const basic = "k8s.io/gengo/v2/parser/testdata/basic"
+	const generic = "k8s.io/gengo/v2/parser/testdata/generic-decls"
+	outDir := t.TempDir()
+
+	universes := map[string]types.Universe{}
+	inputs := map[string][]string{}
+	plugin := func(name, pattern string, gen generator.Generator) Plugin {
+		return Plugin{
+			Name:          name,
+			NameSystems:   namer.NameSystems{"public": namer.NewPublicNamer(0)},
+			DefaultSystem: "public",
+			Patterns:      []string{pattern},
+			GetTargets: func(c *generator.Context) []generator.Target {
+				universes[name] = c.Universe
+				inputs[name] = c.Inputs
+				return []generator.Target{generator.SimpleTarget{
+					PkgName:       "out",
+					PkgPath:       "example.com/out",
+					PkgDir:        filepath.Join(outDir, name),
+					HeaderComment: []byte("// Code generated by test. DO NOT EDIT.\n\n"),
+					GeneratorsFunc: func(*generator.Context) []generator.Generator {
+						return []generator.Generator{gen}
+					},
+				}}
+			},
+		}
+	}
+
+	err := ExecutePlugins([]Plugin{
+		plugin("fails", "./parser/testdata/generic-decls", failingGenerator{generator.GoGenerator{OutputFilename: "fails.go"}}),
+		plugin("works", "./parser/testdata/basic", generator.GoGenerator{OutputFilename: "works.go", OptionalBody: []byte("var _ = 1\n")}),
+	}, Options{})
+
+	var pluginErr *PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.Plugin != "fails" {
+		t.Fatalf("expected an error from plugin fails, got %v", err)
+	}
+	// The other plugin was executed all the same.
+	if _, err := os.Stat(filepath.Join(outDir, "works", "works.go")); err != nil {
+		t.Errorf("expected plugin works to write its file: %v", err)
+	}
+
+	// The plugins share one parse of all of their packages, but each has its
+	// own inputs.
+	if reflect.ValueOf(universes["fails"]).Pointer() != reflect.ValueOf(universes["works"]).Pointer() {
+		t.Errorf("expected the plugins to share a Universe")
+	}
+	for _, pkgPath := range []string{basic, generic} {
+		if pkg := universes["works"][pkgPath]; pkg == nil || len(pkg.Types) == 0 {
+			t.Errorf("expected package %s to be parsed", pkgPath)
+		}
+	}
+	if want, got := []string{generic}, inputs["fails"]; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong inputs for plugin fails: want %q, got %q", want, got)
+	}
+	if want, got := []string{basic}, inputs["works"]; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong inputs for plugin works: want %q, got %q", want, got)
+	}
+
+	dup := plugin("works", "./parser/testdata/basic", generator.GoGenerator{OutputFilename: "works.go"})
+	if err := ExecutePlugins([]Plugin{dup, dup}, Options{}); err == nil {
+		t.Errorf("expected an error for duplicate plugin names")
+	}
+}
+
+func TestExecutePluginsCleanup(t *testing.T) {
+	outDir := t.TempDir()
+	header, err := GoBoilerplate("", "", StdGeneratedBy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Both plugins generate into one directory, with the same header.
+	plugin := func(name string) Plugin {
+		return Plugin{
+			Name:          name,
+			NameSystems:   namer.NameSystems{"public": namer.NewPublicNamer(0)},
+			DefaultSystem: "public",
+			Patterns:      []string{"./parser/testdata/basic"},
+			GetTargets: func(*generator.Context) []generator.Target {
+				return []generator.Target{generator.SimpleTarget{
+					PkgName:       "out",
+					PkgPath:       "example.com/out",
+					PkgDir:        outDir,
+					HeaderComment: header,
+					GeneratorsFunc: func(*generator.Context) []generator.Generator {
+						return []generator.Generator{generator.GoGenerator{OutputFilename: "zz_generated." + name + ".go"}}
+					},
+				}}
+			},
+		}
+	}
+	plugins := []Plugin{plugin("deepcopy"), plugin("defaults")}
+	stale := filepath.Join(outDir, "zz_generated.conversion.go")
+	if err := os.WriteFile(stale, append(header, "package out\n"...), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := Options{Cleanup: true, GeneratedBy: StdGeneratedBy}
+	if err := ExecutePlugins(plugins, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"zz_generated.deepcopy.go", "zz_generated.defaults.go"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the orphaned file to be removed, got %v", err)
+	}
+
+	// Nor when the plugins' targets are skipped as up to date.
+	opts.CacheFile = filepath.Join(t.TempDir(), "cache")
+	for range 2 {
+		if err := ExecutePlugins(plugins, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, name := range []string{"zz_generated.deepcopy.go", "zz_generated.defaults.go"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("expected %s to be kept with a cache: %v", name, err)
+		}
+	}
+
+	// Verify mode doesn't report the plugins' files either.
+	opts = Options{Verify: true, GeneratedBy: StdGeneratedBy}
+	if err := ExecutePlugins(plugins, opts); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(stale, append(header, "package out\n"...), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var verifyErr *generator.VerifyError
+	if err := ExecutePlugins(plugins, opts); !errors.As(err, &verifyErr) {
+		t.Errorf("expected a verify error, got %v", err)
+	} else if want, got := []string{stale}, verifyErr.Paths(generator.ExtraFile); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong extra files: want %q, got %q", want, got)
+	}
+}
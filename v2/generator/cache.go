@@ -0,0 +1,331 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"k8s.io/gengo/v2/types"
+	"k8s.io/klog/v2"
+)
+
+// ExecutionCache lets ExecuteTargets skip targets which are already up to
+// date (see Context.Cache). For each target, it records a hash of the
+// target's inputs and the hashes of the files the target wrote. If, on a later
+// run, the inputs hash the same and those files are unchanged, the target is
+// not executed.
+//
+// A target's inputs are the Go source files of its own package and of every
+// package reachable from the types which pass its Filter (through members,
+// elements, methods, and so on), plus the cache's Identity. Generators which
+// look at other parts of the Universe should not be used with a cache, or
+// should include whatever else they depend on in the Identity.
+//
+// An ExecutionCache is safe for concurrent use.
+type ExecutionCache struct {
+	// Identity describes everything other than the inputs which affects the
+	// output, e.g. the name, version and arguments of the tool, and the
+	// build tags. If it differs from the identity with which the cache was
+	// saved, the saved entries are discarded.
+	Identity string
+
+	// Overlay maps file paths to the contents which were parsed in place
+	// of those files' contents on disk (see parser.Options.Overlay). These
+	// contents are what is hashed for those files.
+	Overlay map[string][]byte
+
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+	// Paths which any target wrote the last time it was executed. These are
+	// never considered to be inputs, even if they are part of an input
+	// package, so that generating into an input package does not
+	// invalidate the cache.
+	outputs map[string]bool
+	// Hashes of input files, so each is only read once per run.
+	fileHashes map[string]string
+}
+
+// cacheEntry is the cached state of one target.
+type cacheEntry struct {
+	Inputs string `json:"inputs"`
+	// Output file paths to the hash of their content.
+	Outputs map[string]string `json:"outputs"`
+	// What the target's files were generated from, so the Manifest can
+	// still describe them when the target is skipped.
+	Files []ManifestFile `json:"files,omitempty"`
+}
+
+// cacheFile is the serialized form of an ExecutionCache.
+type cacheFile struct {
+	Identity string                 `json:"identity"`
+	Targets  map[string]*cacheEntry `json:"targets"`
+}
+
+// NewExecutionCache returns an empty ExecutionCache.
+func NewExecutionCache(identity string) *ExecutionCache {
+	return &ExecutionCache{
+		Identity:   identity,
+		entries:    map[string]*cacheEntry{},
+		outputs:    map[string]bool{},
+		fileHashes: map[string]string{},
+	}
+}
+
+// LoadExecutionCache reads an ExecutionCache which was saved to path. If the
+// file does not exist, was saved with a different identity, or can't be
+// decoded, an empty cache is returned.
+func LoadExecutionCache(path, identity string) (*ExecutionCache, error) {
+	ec := NewExecutionCache(identity)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ec, nil
+	} else if err != nil {
+		return nil, err
+	}
+	cf := cacheFile{}
+	if err := json.Unmarshal(b, &cf); err != nil {
+		klog.Warningf("Ignoring unreadable cache file %q: %v", path, err)
+		return ec, nil
+	}
+	if cf.Identity != identity {
+		klog.V(2).Infof("Ignoring cache file %q from a different configuration", path)
+		return ec, nil
+	}
+	for key, entry := range cf.Targets {
+		if entry == nil {
+			continue
+		}
+		ec.entries[key] = entry
+		for path := range entry.Outputs {
+			ec.outputs[path] = true
+		}
+	}
+	return ec, nil
+}
+
+// Save writes the cache to path.
+func (ec *ExecutionCache) Save(path string) error {
+	ec.lock.Lock()
+	cf := cacheFile{Identity: ec.Identity, Targets: maps.Clone(ec.entries)}
+	ec.lock.Unlock()
+
+	b, err := json.MarshalIndent(cf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return OSFileSystem{}.WriteFile(path, append(b, '\n'))
+}
+
+// cacheKey identifies a target in the cache.
+func cacheKey(tgt Target) string {
+	return fmt.Sprintf("%s (%s)", tgt.Path(), tgt.Dir())
+}
+
+// inputHash hashes the inputs of a target. c is the target's context, i.e.
+// filtered by the target.
+func (ec *ExecutionCache) inputHash(c *Context, tgt Target) (string, error) {
+	pkgs := map[string]bool{tgt.Path(): true}
+	seen := map[*types.Type]bool{}
+	var visit func(t *types.Type)
+	visit = func(t *types.Type) {
+		if t == nil || seen[t] {
+			return
+		}
+		seen[t] = true
+		if t.Name.Package != "" {
+			pkgs[t.Name.Package] = true
+		}
+		for _, m := range t.Members {
+			visit(m.Type)
+		}
+		for _, tp := range t.TypeParams {
+			visit(tp)
+		}
+		for _, arg := range t.TypeArgs {
+			visit(arg)
+		}
+		visit(t.Origin)
+		visit(t.Constraint)
+		visit(t.Elem)
+		visit(t.Key)
+		visit(t.Underlying)
+		for _, m := range t.Methods {
+			visit(m)
+		}
+		for _, e := range t.EmbeddedInterfaces {
+			visit(e)
+		}
+		for _, union := range t.Unions {
+			for _, term := range union {
+				visit(term.Type)
+			}
+		}
+		if sig := t.Signature; sig != nil {
+			visit(sig.Receiver)
+			for _, p := range sig.Parameters {
+				visit(p.Type)
+			}
+			for _, r := range sig.Results {
+				visit(r.Type)
+			}
+		}
+	}
+	for _, t := range c.Order {
+		visit(t)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "identity %q\ntarget %q %q %q\n", ec.Identity, tgt.Name(), tgt.Path(), tgt.Dir())
+	for _, path := range slices.Sorted(maps.Keys(pkgs)) {
+		fmt.Fprintf(h, "package %q\n", path)
+		// Don't use Universe.Package, which would add the package.
+		pkg := c.Universe[path]
+		if pkg == nil {
+			continue
+		}
+		for _, file := range slices.Sorted(slices.Values(slices.Concat(pkg.GoFiles, pkg.TestGoFiles))) {
+			if ec.isOutput(file) {
+				continue
+			}
+			fileHash, err := ec.hashFile(file)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "file %q %s\n", file, fileHash)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (ec *ExecutionCache) isOutput(path string) bool {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+	return ec.outputs[path]
+}
+
+func (ec *ExecutionCache) hashFile(path string) (string, error) {
+	ec.lock.Lock()
+	fileHash, found := ec.fileHashes[path]
+	ec.lock.Unlock()
+	if found {
+		return fileHash, nil
+	}
+
+	b, found := ec.Overlay[path]
+	if !found {
+		var err error
+		if b, err = os.ReadFile(path); err != nil {
+			return "", err
+		}
+	}
+	fileHash = hashBytes(b)
+
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+	ec.fileHashes[path] = fileHash
+	return fileHash, nil
+}
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// upToDate returns true if tgt was last executed with the same inputs, and
+// the files it wrote then are still in fsys, unchanged and with the current
+// header. If so, it also returns the manifest records for those files.
+func (ec *ExecutionCache) upToDate(fsys OutputFS, tgt Target, inputs string) ([]ManifestFile, bool) {
+	ec.lock.Lock()
+	entry := ec.entries[cacheKey(tgt)]
+	ec.lock.Unlock()
+
+	if entry == nil || entry.Inputs != inputs {
+		return nil, false
+	}
+	for path, outputHash := range entry.Outputs {
+		b, err := fsys.ReadFile(path)
+		if err != nil || hashBytes(b) != outputHash || !bytes.HasPrefix(b, tgt.Header(filepath.Base(path))) {
+			return nil, false
+		}
+	}
+	return entry.Files, true
+}
+
+// update records that tgt was executed with the specified inputs, and wrote
+// the specified files, which are described by manifestFiles. If written is
+// nil, the target's entry is removed.
+func (ec *ExecutionCache) update(tgt Target, inputs string, written map[string][]byte, manifestFiles []ManifestFile) {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+
+	key := cacheKey(tgt)
+	if written == nil {
+		delete(ec.entries, key)
+		return
+	}
+	entry := &cacheEntry{Inputs: inputs, Outputs: map[string]string{}, Files: manifestFiles}
+	for path, b := range written {
+		entry.Outputs[path] = hashBytes(b)
+	}
+	ec.entries[key] = entry
+}
+
+// recordingFS is an OutputFS which remembers what was written through it.
+type recordingFS struct {
+	OutputFS
+
+	lock    sync.Mutex
+	written map[string][]byte
+}
+
+func (r *recordingFS) WriteFile(name string, data []byte) error {
+	if err := r.OutputFS.WriteFile(name, data); err != nil {
+		return err
+	}
+	r.record(name, data)
+	return nil
+}
+
+// record remembers that data was written to the named file.
+func (r *recordingFS) record(name string, data []byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.written[name] = data
+}
+
+// contentOf returns what was written to the named file. r may be nil, in
+// which case nothing was recorded.
+func (r *recordingFS) contentOf(name string) ([]byte, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	content, ok := r.written[name]
+	return content, ok
+}
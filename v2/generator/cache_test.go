@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/gengo/v2/generator"
+	"k8s.io/gengo/v2/types"
+)
+
+func TestExecutionCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheFile := filepath.Join(dir, "cache.json")
+	outDir := filepath.Join(dir, "out")
+	inFile := filepath.Join(dir, "in", "in.go")
+	unrelatedFile := filepath.Join(dir, "unrelated", "unrelated.go")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(inFile, "package in\n\ntype Foo struct{}\n")
+	write(unrelatedFile, "package unrelated\n")
+
+	u := types.Universe{}
+	u.Package("example.com/in").GoFiles = []string{inFile}
+	u.Package("example.com/unrelated").GoFiles = []string{unrelatedFile}
+	foo := u.Type(types.Name{Package: "example.com/in", Name: "Foo"})
+
+	executions := 0
+	tgt := generator.SimpleTarget{
+		PkgName:       "foo",
+		PkgPath:       "example.com/foo",
+		PkgDir:        outDir,
+		HeaderComment: []byte(testHeader),
+		GeneratorsFunc: func(*generator.Context) []generator.Generator {
+			executions++
+			return []generator.Generator{generator.GoGenerator{OutputFilename: "a.go", OptionalBody: []byte("var _ = 1\n")}}
+		},
+	}
+
+	var overlay map[string][]byte
+	run := func(identity string) {
+		t.Helper()
+		cache, err := generator.LoadExecutionCache(cacheFile, identity)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c := newTestContext()
+		c.Universe = u
+		c.Order = []*types.Type{foo}
+		cache.Overlay = overlay
+		c.Cache = cache
+		if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := cache.Save(cacheFile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expect := func(step string, want int) {
+		t.Helper()
+		if executions != want {
+			t.Errorf("%s: expected %d executions, got %d", step, want, executions)
+		}
+	}
+
+	run("v1")
+	expect("first run", 1)
+	run("v1")
+	expect("no changes", 1)
+
+	write(unrelatedFile, "package unrelated\n\nvar X int\n")
+	run("v1")
+	expect("unrelated change", 1)
+
+	write(inFile, "package in\n\ntype Foo struct{ X int }\n")
+	run("v1")
+	expect("input changed", 2)
+	run("v1")
+	expect("no changes", 2)
+
+	overlay = map[string][]byte{inFile: []byte("package in\n\ntype Foo struct{ Y int }\n")}
+	run("v1")
+	expect("input overlaid", 3)
+	run("v1")
+	expect("no changes", 3)
+	overlay = nil
+	run("v1")
+	expect("overlay removed", 4)
+
+	write(filepath.Join(outDir, "a.go"), testHeader+"package foo\n")
+	run("v1")
+	expect("output changed", 5)
+
+	if err := os.Remove(filepath.Join(outDir, "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	run("v1")
+	expect("output removed", 6)
+
+	run("v2")
+	expect("identity changed", 7)
+	run("v2")
+	expect("no changes", 7)
+
+	write(cacheFile, "not json")
+	run("v2")
+	expect("corrupt cache", 8)
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The number of unchanged lines to show around each change in a diff.
+const diffContext = 3
+
+// Diff returns a unified diff which turns the file on disk into the generated
+// output. A missing file is diffed against /dev/null as the "old" side, and an
+// extra file is diffed against /dev/null as the "new" side.
+func (e *MismatchError) Diff() []byte {
+	oldName, newName := e.Path, e.Path
+	switch e.Kind {
+	case MissingFile:
+		oldName = "/dev/null"
+	case ExtraFile:
+		newName = "/dev/null"
+	}
+	return unifiedDiff(oldName, newName, e.Existing, e.Generated)
+}
+
+// WriteDiff writes a unified diff for every mismatched file to w. The result
+// is a single patch which, when applied, brings the files on disk up to date.
+func (e *VerifyError) WriteDiff(w io.Writer) error {
+	for _, m := range e.Mismatches {
+		if _, err := w.Write(m.Diff()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type diffOpKind byte
+
+const (
+	diffEqual  diffOpKind = ' '
+	diffDelete diffOpKind = '-'
+	diffInsert diffOpKind = '+'
+)
+
+type diffOp struct {
+	kind diffOpKind
+	line string // includes the trailing newline, if there was one
+}
+
+// unifiedDiff produces a unified diff between a and b, in the format
+// understood by `patch` and `git apply`. It returns nil if they are equal.
+func unifiedDiff(aName, bName string, a, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	ops := diffLines(splitLinesKeepEOL(a), splitLinesKeepEOL(b))
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", aName, bName)
+
+	// aLine and bLine are the 0-based line numbers of ops[i] in a and b.
+	aLine, bLine := 0, 0
+	for i := 0; i < len(ops); {
+		if ops[i].kind == diffEqual {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+
+		// Found a change. Back up to include leading context, then extend
+		// forward until there are enough equal lines to end the hunk.
+		start := i
+		for n := 0; n < diffContext && start > 0 && ops[start-1].kind == diffEqual; n++ {
+			start--
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != diffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == diffEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end = min(run, end+diffContext)
+				break
+			}
+			end = run
+		}
+
+		hunkA, hunkB := aLine-(i-start), bLine-(i-start)
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != diffInsert {
+				aCount++
+			}
+			if op.kind != diffDelete {
+				bCount++
+			}
+		}
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(hunkA, aCount), hunkRange(hunkB, bCount))
+		for _, op := range ops[start:end] {
+			out.WriteByte(byte(op.kind))
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		// Advance past the hunk, keeping line numbers in sync.
+		for ; i < end; i++ {
+			if ops[i].kind != diffInsert {
+				aLine++
+			}
+			if ops[i].kind != diffDelete {
+				bLine++
+			}
+		}
+	}
+	return out.Bytes()
+}
+
+// hunkRange formats the 0-based start line and count of a hunk.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range names the line before the change.
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLinesKeepEOL(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a minimal line-based edit script which turns a into b,
+// using the linear space variant of Myers' O(ND) algorithm, which finds the
+// middle of an optimal path and recurses on either side of it. The memory it
+// needs is proportional to the number of lines, however many differ.
+func diffLines(a, b []string) []diffOp {
+	size := len(a) + len(b) + 2
+	d := &lineDiffer{
+		a:       a,
+		b:       b,
+		forward: make([]int, 2*size+1),
+		reverse: make([]int, 2*size+1),
+		offset:  size,
+	}
+	d.diff(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// lineDiffer holds the state of diffLines.
+type lineDiffer struct {
+	a, b []string
+	ops  []diffOp
+
+	// The furthest reaching paths of the forward and reverse searches,
+	// indexed by diagonal plus offset. They are reused by every call to
+	// middleSnake.
+	forward, reverse []int
+	offset           int
+}
+
+// diff appends the edits which turn a[aLo:aHi] into b[bLo:bHi].
+func (d *lineDiffer) diff(aLo, aHi, bLo, bHi int) {
+	// Lines in common at the start and end can be skipped, which handles
+	// most generated files in linear time.
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, diffOp{diffEqual, d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	aEnd, bEnd := aHi, bHi
+	for aLo < aEnd && bLo < bEnd && d.a[aEnd-1] == d.b[bEnd-1] {
+		aEnd--
+		bEnd--
+	}
+
+	switch {
+	case aLo == aEnd:
+		for _, line := range d.b[bLo:bEnd] {
+			d.ops = append(d.ops, diffOp{diffInsert, line})
+		}
+	case bLo == bEnd:
+		for _, line := range d.a[aLo:aEnd] {
+			d.ops = append(d.ops, diffOp{diffDelete, line})
+		}
+	default:
+		// Both sides are left, and differ at both ends, so at least two
+		// edits are needed, and each half of the path needs fewer.
+		x, y, u, v := d.middleSnake(aLo, aEnd, bLo, bEnd)
+		d.diff(aLo, x, bLo, y)
+		for _, line := range d.a[x:u] {
+			d.ops = append(d.ops, diffOp{diffEqual, line})
+		}
+		d.diff(u, aEnd, v, bEnd)
+	}
+
+	for _, line := range d.a[aEnd:aHi] {
+		d.ops = append(d.ops, diffOp{diffEqual, line})
+	}
+}
+
+// middleSnake finds the snake (a run of equal lines, possibly empty) in the
+// middle of an optimal path from (aLo, bLo) to (aHi, bHi), by searching from
+// both ends at once until the paths overlap. It returns the start (x, y) and
+// end (u, v) of the snake.
+func (d *lineDiffer) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	// Diagonal k of the forward search is diagonal delta-k of the reverse
+	// search, which runs on the reversed sequences.
+	fwd, rev, o := d.forward, d.reverse, d.offset
+	fwd[o+1], rev[o+1] = 0, 0
+
+	for D := 0; D <= (n+m+1)/2; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && fwd[o+k-1] < fwd[o+k+1]) {
+				x = fwd[o+k+1] // down: insertion
+			} else {
+				x = fwd[o+k-1] + 1 // right: deletion
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			fwd[o+k] = x
+			if odd && delta-k >= -(D-1) && delta-k <= D-1 && x+rev[o+delta-k] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && rev[o+k-1] < rev[o+k+1]) {
+				x = rev[o+k+1]
+			} else {
+				x = rev[o+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			rev[o+k] = x
+			if !odd && delta-k >= -D && delta-k <= D && x+fwd[o+delta-k] >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY
+			}
+		}
+	}
+	// Not reached: the searches always meet.
+	panic("diffLines: no middle snake")
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func lines(in ...string) []byte {
+	return []byte(strings.Join(in, "\n") + "\n")
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     []byte
+		expected string
+	}{{
+		name:     "equal",
+		a:        lines("a", "b"),
+		b:        lines("a", "b"),
+		expected: "",
+	}, {
+		name: "new file",
+		a:    nil,
+		b:    lines("a", "b"),
+		expected: `--- a
++++ b
+@@ -0,0 +1,2 @@
++a
++b
+`,
+	}, {
+		name: "deleted file",
+		a:    lines("a"),
+		b:    nil,
+		expected: `--- a
++++ b
+@@ -1 +0,0 @@
+-a
+`,
+	}, {
+		name: "change in the middle",
+		a:    lines("1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		b:    lines("1", "2", "3", "4", "five", "6", "7", "8", "9"),
+		expected: `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+	}, {
+		name: "two hunks",
+		a:    lines("1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"),
+		b:    lines("one", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13"),
+		expected: `--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`,
+	}, {
+		name: "missing newline",
+		a:    []byte("a\nb"),
+		b:    []byte("a\nb\n"),
+		expected: `--- a
++++ b
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if want, got := tc.expected, string(unifiedDiff("a", "b", tc.a, tc.b)); want != got {
+				t.Errorf("wrong diff:\nwant:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// lcs returns the length of the longest common subsequence of a and b,
+	// which determines the number of edits in a minimal script.
+	lcs := func(a, b []string) int {
+		prev := make([]int, len(b)+1)
+		for i := range a {
+			cur := make([]int, len(b)+1)
+			for j := range b {
+				if a[i] == b[j] {
+					cur[j+1] = prev[j] + 1
+				} else {
+					cur[j+1] = max(prev[j+1], cur[j])
+				}
+			}
+			prev = cur
+		}
+		return prev[len(b)]
+	}
+	random := func(r *rand.Rand) []string {
+		out := make([]string, r.Intn(30))
+		for i := range out {
+			out[i] = string(rune('a' + r.Intn(4)))
+		}
+		return out
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		a, b := random(r), random(r)
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != diffInsert {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != diffDelete {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind != diffEqual {
+				edits++
+			}
+		}
+		if fmt.Sprint(gotA) != fmt.Sprint(a) || fmt.Sprint(gotB) != fmt.Sprint(b) {
+			t.Fatalf("diff of %q and %q does not produce them: got %q and %q", a, b, gotA, gotB)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("diff of %q and %q is not minimal: want %d edits, got %d", a, b, want, edits)
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 100000; i++ {
+		a = append(a, fmt.Sprintf("a%d\n", i))
+		b = append(b, fmt.Sprintf("b%d\n", i))
+	}
+	// Missing and extra files, and completely different ones, must not
+	// need memory for every step of the search.
+	for _, tc := range []struct {
+		name string
+		a, b []string
+	}{
+		{"missing", nil, b},
+		{"extra", a, nil},
+		{"different", a[:5000], b[:5000]},
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if ops := diffLines(tc.a, tc.b); len(ops) != len(tc.a)+len(tc.b) {
+			t.Errorf("%s: expected %d edits, got %d", tc.name, len(tc.a)+len(tc.b), len(ops))
+		}
+		runtime.ReadMemStats(&after)
+		// Most of this is the edit script itself.
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+			t.Errorf("%s: allocated too much memory: %d bytes", tc.name, allocated)
+		}
+	}
+}
+
+func TestVerifyErrorWriteDiff(t *testing.T) {
+	verifyErr := &VerifyError{}
+	verifyErr.add(
+		&MismatchError{Kind: MissingFile, Path: "dir/b.go", Generated: lines("package b")},
+		&MismatchError{Kind: ExtraFile, Path: "dir/c.go", Existing: lines("package c")},
+		&MismatchError{Kind: StaleFile, Path: "dir/a.go", Existing: lines("package a"), Generated: lines("package aa")},
+	)
+	buf := &bytes.Buffer{}
+	if err := verifyErr.WriteDiff(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `--- dir/a.go
++++ dir/a.go
+@@ -1 +1 @@
+-package a
++package aa
+--- /dev/null
++++ dir/b.go
+@@ -0,0 +1 @@
++package b
+--- dir/c.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package c
+`
+	if want, got := expected, buf.String(); want != got {
+		t.Errorf("wrong diff:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
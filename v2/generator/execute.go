@@ -21,10 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"golang.org/x/tools/imports"
 
@@ -37,11 +40,52 @@ import (
 func (c *Context) ExecuteTargets(targets []Target) error {
 	klog.V(5).Infof("ExecuteTargets: %d targets", len(targets))
 
+	type result struct {
+		mismatches []*MismatchError
+		err        error
+	}
+	results := make([]result, len(targets))
+	if c.Parallelism < 2 {
+		for i, tgt := range targets {
+			results[i].mismatches, results[i].err = c.executeTarget(tgt)
+		}
+	} else {
+		// Results are stored by index, so that they are reported in target
+		// order no matter which worker finishes first.
+		next := make(chan int)
+		wg := sync.WaitGroup{}
+		for range min(c.Parallelism, len(targets)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				wc := *c
+				wc.Namers = c.Namers.Clone()
+				wc.parallel = true
+				for i := range next {
+					results[i].mismatches, results[i].err = wc.executeTarget(targets[i])
+				}
+			}()
+		}
+		for i := range targets {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
 	var errs []error
-	for _, tgt := range targets {
-		if err := c.ExecuteTarget(tgt); err != nil {
-			errs = append(errs, err)
+	verifyErr := &VerifyError{}
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err)
 		}
+		verifyErr.add(r.mismatches...)
+	}
+	if len(verifyErr.Mismatches) > 0 {
+		if len(errs) == 0 {
+			return verifyErr
+		}
+		errs = append(errs, verifyErr)
 	}
 	if len(errs) > 0 {
 		return fmt.Errorf("some targets had errors: %w", errors.Join(errs...))
@@ -49,19 +93,87 @@ func (c *Context) ExecuteTargets(targets []Target) error {
 	return nil
 }
 
+// FormatFailurePolicy says what DefaultFileType.AssembleFileTo does with the
+// output when it can't be formatted. Whatever the policy, the formatting error
+// is returned.
+type FormatFailurePolicy string
+
+const (
+	// WriteUnformatted writes the unformatted output in place of the file,
+	// so it is easy to see what's going wrong and fix the generator. This
+	// is the default.
+	WriteUnformatted FormatFailurePolicy = ""
+	// KeepExisting leaves the existing file (if any) alone.
+	KeepExisting FormatFailurePolicy = "keep"
+	// WriteSidecar leaves the existing file (if any) alone, and writes the
+	// unformatted output next to it, with FailedFileSuffix appended to the
+	// name. The sidecar file is removed the next time the file is written
+	// successfully.
+	WriteSidecar FormatFailurePolicy = "sidecar"
+)
+
+// FailedFileSuffix is appended to the name of a file to get the name of the
+// sidecar file written by the WriteSidecar policy.
+const FailedFileSuffix = ".gengo-failed"
+
 type DefaultFileType struct {
 	Format   func([]byte) ([]byte, error)
 	Assemble func(io.Writer, *File)
+
+	// What to do if Format fails.
+	OnFormatFailure FormatFailurePolicy
 }
 
+var _ FSFileType = DefaultFileType{}
+var _ VerifyingFileType = DefaultFileType{}
+
 func (ft DefaultFileType) AssembleFile(f *File, pathname string) error {
+	return ft.AssembleFileTo(OSFileSystem{}, f, pathname)
+}
+
+func (ft DefaultFileType) AssembleFileTo(fsys OutputFS, f *File, pathname string) error {
 	klog.V(5).Infof("Assembling file %q", pathname)
 
-	destFile, err := os.Create(pathname)
+	b := &bytes.Buffer{}
+	et := NewErrorTracker(b)
+	ft.Assemble(et, f)
+	if et.Error() != nil {
+		return et.Error()
+	}
+	formatted, err := ft.Format(b.Bytes())
 	if err != nil {
+		err = fmt.Errorf("unable to format file %q (%v)", pathname, err)
+		switch ft.OnFormatFailure {
+		case WriteUnformatted:
+			// Write the file anyway, so they can see what's going wrong and fix the generator.
+			if err2 := fsys.WriteFile(pathname, b.Bytes()); err2 != nil {
+				return err2
+			}
+		case KeepExisting:
+		case WriteSidecar:
+			sidecar := pathname + FailedFileSuffix
+			if err2 := fsys.WriteFile(sidecar, b.Bytes()); err2 != nil {
+				return err2
+			}
+			err = fmt.Errorf("%w; unformatted output is in %q", err, sidecar)
+		default:
+			err = fmt.Errorf("%w; unknown format failure policy %q", err, ft.OnFormatFailure)
+		}
 		return err
 	}
-	defer destFile.Close()
+	if err := fsys.WriteFile(pathname, formatted); err != nil {
+		return err
+	}
+	if ft.OnFormatFailure == WriteSidecar {
+		if err := fsys.Remove(pathname + FailedFileSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ft DefaultFileType) VerifyFile(fsys OutputFS, f *File, pathname string) error {
+	klog.V(5).Infof("Verifying file %q", pathname)
 
 	b := &bytes.Buffer{}
 	et := NewErrorTracker(b)
@@ -69,17 +181,90 @@ func (ft DefaultFileType) AssembleFile(f *File, pathname string) error {
 	if et.Error() != nil {
 		return et.Error()
 	}
-	if formatted, err := ft.Format(b.Bytes()); err != nil {
-		err = fmt.Errorf("unable to format file %q (%v)", pathname, err)
-		// Write the file anyway, so they can see what's going wrong and fix the generator.
-		if _, err2 := destFile.Write(b.Bytes()); err2 != nil {
-			return err2
+	formatted, err := ft.Format(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to format file %q (%v)", pathname, err)
+	}
+	return compareFile(fsys, pathname, formatted)
+}
+
+// compareFile compares generated against what is at pathname in fsys.
+func compareFile(fsys OutputFS, pathname string, generated []byte) error {
+	existing, err := fsys.ReadFile(pathname)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &MismatchError{Kind: MissingFile, Path: pathname, Generated: generated}
+	} else if err != nil {
+		return fmt.Errorf("unable to read file %q for comparison: %w", pathname, err)
+	}
+	if !bytes.Equal(generated, existing) {
+		return &MismatchError{Kind: StaleFile, Path: pathname, Existing: existing, Generated: generated}
+	}
+	return nil
+}
+
+// assembleFile writes f to pathname in fsys with ft. If ft is a plain
+// FileType, it writes to the real filesystem, or, if fsys is something else,
+// to a temporary file which is then copied to fsys.
+func assembleFile(ft FileType, fsys OutputFS, f *File, pathname string) error {
+	if ft, ok := ft.(FSFileType); ok {
+		return ft.AssembleFileTo(fsys, f, pathname)
+	}
+	recorder, _ := fsys.(*recordingFS)
+	underlying := fsys
+	if recorder != nil {
+		underlying = recorder.OutputFS
+	}
+	if _, ok := underlying.(OSFileSystem); ok {
+		if err := ft.AssembleFile(f, pathname); err != nil {
+			return err
+		}
+		if recorder != nil {
+			// Record what the FileType wrote, if anything.
+			if content, err := os.ReadFile(pathname); err == nil {
+				recorder.record(pathname, content)
+			}
 		}
+		return nil
+	}
+	content, written, err := assembleTemp(ft, f, pathname)
+	if err != nil || !written {
 		return err
-	} else {
-		_, err = destFile.Write(formatted)
+	}
+	return fsys.WriteFile(pathname, content)
+}
+
+// verifyFile compares f against what is at pathname in fsys, using ft.
+func verifyFile(ft FileType, fsys OutputFS, f *File, pathname string) error {
+	if ft, ok := ft.(VerifyingFileType); ok {
+		return ft.VerifyFile(fsys, f, pathname)
+	}
+	klog.V(5).Infof("Verifying file %q", pathname)
+	generated, written, err := assembleTemp(ft, f, pathname)
+	if err != nil || !written {
 		return err
 	}
+	return compareFile(fsys, pathname, generated)
+}
+
+// assembleTemp assembles f with ft into a temporary directory, under the
+// base name of pathname, and returns what was written, if anything.
+func assembleTemp(ft FileType, f *File, pathname string) ([]byte, bool, error) {
+	dir, err := os.MkdirTemp("", "gengo-")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, filepath.Base(pathname))
+	if err := ft.AssembleFile(f, tmp); err != nil {
+		return nil, false, err
+	}
+	content, err := os.ReadFile(tmp)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
 }
 
 func assembleGoFile(w io.Writer, f *File) {
@@ -117,13 +302,12 @@ func assembleGoFile(w io.Writer, f *File) {
 
 func formatCode(src []byte) ([]byte, error) {
 	// We call goimports because it formats imports better than gofmt, but also
-	// call gofmt because it has the "simplify" logic.  If a gofmt binary is
-	// not found, we will skip it.
+	// apply gofmt's "simplify" logic, which goimports does not.
 	src, err := importsWrapper(src)
 	if err != nil {
 		return nil, err
 	}
-	return gofmtWrapper(src)
+	return gofmtSimplify(src)
 }
 
 func importsWrapper(src []byte) ([]byte, error) {
@@ -136,29 +320,6 @@ func importsWrapper(src []byte) ([]byte, error) {
 	return imports.Process("", src, &opt)
 }
 
-func gofmtWrapper(src []byte) ([]byte, error) {
-	const gofmt = "gofmt"
-
-	if _, err := exec.LookPath(gofmt); err != nil {
-		klog.Errorf("WARNING: skipping output simplification: %v", err)
-		return src, nil
-	}
-
-	cmd := exec.Command(gofmt, "-s")
-	cmd.Stdin = bytes.NewReader(src)
-	stdout := &bytes.Buffer{}
-	cmd.Stdout = stdout
-	stderr := &bytes.Buffer{}
-	cmd.Stderr = stderr
-	if err := cmd.Run(); err != nil {
-		if stderr.Len() > 0 {
-			return nil, fmt.Errorf("%s failed: %v: %s", gofmt, err, strings.TrimSpace(stderr.String()))
-		}
-		return nil, fmt.Errorf("%s failed: %v", gofmt, err)
-	}
-	return stdout.Bytes(), nil
-}
-
 func NewGoFile() *DefaultFileType {
 	return &DefaultFileType{
 		Format:   formatCode,
@@ -207,52 +368,109 @@ func (c *Context) addNameSystems(namers namer.NameSystems) *Context {
 
 // ExecuteTarget runs the generators for a single target.
 func (c *Context) ExecuteTarget(tgt Target) error {
+	mismatches, err := c.executeTarget(tgt)
+	if len(mismatches) == 0 {
+		return err
+	}
+	verifyErr := &VerifyError{}
+	verifyErr.add(mismatches...)
+	if err != nil {
+		return errors.Join(err, verifyErr)
+	}
+	return verifyErr
+}
+
+// executeTarget runs the generators for a single target. In verify mode, any
+// files which do not match are returned, rather than being reported as errors.
+func (c *Context) executeTarget(tgt Target) ([]*MismatchError, error) {
 	tgtDir := tgt.Dir()
 	if tgtDir == "" {
-		return fmt.Errorf("no directory for target %s", tgt.Path())
+		return nil, fmt.Errorf("no directory for target %s", tgt.Path())
 	}
 	klog.V(5).Infof("Executing target %q (%q)", tgt.Name(), tgtDir)
 
 	// Filter out any types the *package* doesn't care about.
 	packageContext := c.filteredBy(tgt.Filter)
 
-	if err := os.MkdirAll(tgtDir, 0755); err != nil {
-		return err
+	fsys := c.outputFS()
+
+	// If the target's inputs and outputs haven't changed, there's nothing
+	// to do.
+	var inputs string
+	if c.Cache != nil && !c.Verify {
+		var err error
+		if inputs, err = c.Cache.inputHash(packageContext, tgt); err != nil {
+			return nil, fmt.Errorf("failed to hash inputs for target %q: %w", tgt.Path(), err)
+		}
+		if manifestFiles, ok := c.Cache.upToDate(fsys, tgt, inputs); ok {
+			klog.V(2).Infof("Skipping target %q: up to date", tgt.Path())
+			if c.Manifest != nil {
+				c.Manifest.add(manifestFiles...)
+			}
+			if c.Orphans != nil {
+				var paths []string
+				for _, f := range manifestFiles {
+					paths = append(paths, f.Path)
+				}
+				c.Orphans.add(paths, nil)
+			}
+			return nil, nil
+		}
+	}
+	// Note what gets written, for the cache and manifest.
+	var recorder *recordingFS
+	if (c.Cache != nil || c.Manifest != nil) && !c.Verify {
+		recorder = &recordingFS{OutputFS: fsys, written: map[string][]byte{}}
+		fsys = recorder
+	}
+
+	if !c.Verify {
+		if err := fsys.MkdirAll(tgtDir); err != nil {
+			return nil, err
+		}
 	}
 
 	files := map[string]*File{}
 	for _, g := range tgt.Generators(packageContext) {
-		// Filter out types the *generator* doesn't care about.
-		genContext := packageContext.filteredBy(g.Filter)
-		// Now add any extra name systems defined by this generator
-		genContext = genContext.addNameSystems(g.Namers(genContext))
-
 		fileType := g.FileType()
 		if len(fileType) == 0 {
-			return fmt.Errorf("generator %q must specify a file type", g.Name())
+			return nil, fmt.Errorf("generator %q must specify a file type", g.Name())
 		}
 		f := files[g.Filename()]
 		if f == nil {
 			// This is the first generator to reference this file, so start it.
 			f = &File{
-				Name:        g.Filename(),
-				FileType:    fileType,
-				PackageName: tgt.Name(),
-				PackagePath: tgt.Path(),
-				PackageDir:  tgt.Dir(),
-				Header:      tgt.Header(g.Filename()),
-				Imports:     map[string]struct{}{},
+				Name:          g.Filename(),
+				FileType:      fileType,
+				PackageName:   tgt.Name(),
+				PackagePath:   tgt.Path(),
+				PackageDir:    tgt.Dir(),
+				Header:        tgt.Header(g.Filename()),
+				Imports:       map[string]struct{}{},
+				importTracker: NewImportTrackerForPackage(tgt.Path()),
+				inputTypes:    map[string]bool{},
 			}
 			files[f.Name] = f
 		} else if f.FileType != g.FileType() {
-			return fmt.Errorf("file %q already has type %q, but generator %q wants to use type %q", f.Name, f.FileType, g.Name(), g.FileType())
+			return nil, fmt.Errorf("file %q already has type %q, but generator %q wants to use type %q", f.Name, f.FileType, g.Name(), g.FileType())
+		}
+
+		// Filter out types the *generator* doesn't care about.
+		genContext := packageContext.filteredBy(g.Filter)
+		genContext.ImportTracker = f.importTracker
+		// Now add any extra name systems defined by this generator
+		genContext = genContext.addNameSystems(g.Namers(genContext))
+
+		f.generators = append(f.generators, g.Name())
+		for _, t := range genContext.Order {
+			f.inputTypes[t.Name.String()] = true
 		}
 
 		if vars := g.PackageVars(genContext); len(vars) > 0 {
 			addIndentHeaderComment(&f.Vars, "Package-wide variables from generator %q.", g.Name())
 			for _, v := range vars {
 				if _, err := fmt.Fprintf(&f.Vars, "%s\n", v); err != nil {
-					return err
+					return nil, err
 				}
 			}
 		}
@@ -260,12 +478,12 @@ func (c *Context) ExecuteTarget(tgt Target) error {
 			addIndentHeaderComment(&f.Consts, "Package-wide consts from generator %q.", g.Name())
 			for _, v := range consts {
 				if _, err := fmt.Fprintf(&f.Consts, "%s\n", v); err != nil {
-					return err
+					return nil, err
 				}
 			}
 		}
 		if err := genContext.executeBody(&f.Body, g); err != nil {
-			return err
+			return nil, err
 		}
 		if imports := g.Imports(genContext); len(imports) > 0 {
 			for _, i := range imports {
@@ -273,22 +491,173 @@ func (c *Context) ExecuteTarget(tgt Target) error {
 			}
 		}
 	}
+	for _, f := range files {
+		for _, i := range f.importTracker.ImportLines() {
+			f.Imports[i] = struct{}{}
+		}
+	}
 
 	var errs []error
-	for _, f := range files {
+	var mismatches []*MismatchError
+	var manifestFiles []ManifestFile
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		f := files[name]
 		finalPath := filepath.Join(tgtDir, f.Name)
 		assembler, ok := c.FileTypes[f.FileType]
 		if !ok {
-			return fmt.Errorf("the file type %q registered for file %q does not exist in the context", f.FileType, f.Name)
+			return nil, fmt.Errorf("the file type %q registered for file %q does not exist in the context", f.FileType, f.Name)
 		}
-		if err := assembler.AssembleFile(f, finalPath); err != nil {
+		if c.Verify {
+			err := verifyFile(assembler, fsys, f, finalPath)
+			var me *MismatchError
+			if errors.As(err, &me) {
+				mismatches = append(mismatches, me)
+			} else if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := assembleFile(assembler, fsys, f, finalPath); err != nil {
+			errs = append(errs, err)
+		} else if content, written := recorder.contentOf(finalPath); written {
+			manifestFiles = append(manifestFiles, manifestFile(f, finalPath, content))
+		}
+	}
+	if c.Verify || c.Cleanup {
+		orphans, err := findOrphanedFiles(fsys, tgt, files, c.GeneratedBy)
+		if err != nil {
 			errs = append(errs, err)
 		}
+		switch {
+		case c.Orphans != nil:
+			var paths []string
+			for name := range files {
+				paths = append(paths, filepath.Join(tgtDir, name))
+			}
+			c.Orphans.add(paths, orphans)
+		case c.Verify:
+			mismatches = append(mismatches, orphans...)
+		default:
+			for _, o := range orphans {
+				klog.V(2).Infof("Removing orphaned file %q", o.Path)
+				if err := fsys.Remove(o.Path); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	if c.Manifest != nil {
+		c.Manifest.add(manifestFiles...)
+	}
+	if c.Cache != nil && !c.Verify {
+		if len(errs) > 0 {
+			c.Cache.update(tgt, inputs, nil, nil)
+		} else {
+			c.Cache.update(tgt, inputs, recorder.written, manifestFiles)
+		}
 	}
 	if len(errs) > 0 {
-		return fmt.Errorf("errors in target %q: %w", tgt.Path(), errors.Join(errs...))
+		return mismatches, fmt.Errorf("errors in target %q: %w", tgt.Path(), errors.Join(errs...))
 	}
-	return nil
+	return mismatches, nil
+}
+
+// OrphanSet collects the orphaned files (see Context.Cleanup) which are found
+// by one or more Contexts, and the files which they generate, so that the
+// orphans can be handled once all of them have been executed. This is for
+// Contexts which generate into the same directories, whose files would
+// otherwise look orphaned to each other. It is safe for concurrent use.
+type OrphanSet struct {
+	lock      sync.Mutex
+	generated map[string]bool
+	orphans   map[string]*MismatchError
+}
+
+// NewOrphanSet makes an empty OrphanSet.
+func NewOrphanSet() *OrphanSet {
+	return &OrphanSet{
+		generated: map[string]bool{},
+		orphans:   map[string]*MismatchError{},
+	}
+}
+
+// add records that the files at the specified paths were generated, and
+// that orphans were found.
+func (o *OrphanSet) add(generated []string, orphans []*MismatchError) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, path := range generated {
+		o.generated[path] = true
+	}
+	for _, orphan := range orphans {
+		o.orphans[orphan.Path] = orphan
+	}
+}
+
+// Orphans returns the orphaned files which were not generated by any of the
+// Contexts, as ExtraFile mismatches sorted by path.
+func (o *OrphanSet) Orphans() []*MismatchError {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	var out []*MismatchError
+	for _, path := range slices.Sorted(maps.Keys(o.orphans)) {
+		if !o.generated[path] {
+			out = append(out, o.orphans[path])
+		}
+	}
+	return out
+}
+
+// findOrphanedFiles looks for files in the target's directory which were not
+// generated in this run, but which were generated previously by the running
+// tool: they carry the generatedBy line before their package clause. They are
+// returned as ExtraFile mismatches. If generatedBy is empty, no files are
+// recognized, since a header alone (e.g. a license) might also start files
+// which were written by hand.
+func findOrphanedFiles(fsys OutputFS, tgt Target, generated map[string]*File, generatedBy string) ([]*MismatchError, error) {
+	generatedBy = strings.TrimSpace(generatedBy)
+	if generatedBy == "" {
+		return nil, nil
+	}
+	entries, err := fsys.ReadDir(tgt.Dir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var orphans []*MismatchError
+	for _, e := range entries {
+		if !e.Type().IsRegular() || generated[e.Name()] != nil || strings.HasSuffix(e.Name(), FailedFileSuffix) {
+			continue
+		}
+		pathname := filepath.Join(tgt.Dir(), e.Name())
+		existing, err := fsys.ReadFile(pathname)
+		if err != nil {
+			return nil, err
+		}
+		if hasGeneratedByLine(existing, generatedBy) {
+			orphans = append(orphans, &MismatchError{Kind: ExtraFile, Path: pathname, Existing: existing})
+		}
+	}
+	return orphans, nil
+}
+
+// hasGeneratedByLine returns true if generatedBy appears as a line of its own
+// in src, before the package clause.
+func hasGeneratedByLine(src []byte, generatedBy string) bool {
+	if generatedBy == "" {
+		return false
+	}
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		if line == generatedBy {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false
 }
 
 func (c *Context) executeBody(w io.Writer, generator Generator) error {
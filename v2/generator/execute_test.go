@@ -0,0 +1,395 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/gengo/v2/generator"
+	"k8s.io/gengo/v2/namer"
+	"k8s.io/gengo/v2/types"
+)
+
+const testGeneratedBy = "// Code generated by test. DO NOT EDIT."
+
+const testHeader = testGeneratedBy + "\n\n"
+
+func newTestContext() *generator.Context {
+	return &generator.Context{
+		FileTypes: map[string]generator.FileType{
+			generator.GoFileType: generator.NewGoFile(),
+		},
+	}
+}
+
+func newTestTarget(dir string, files ...string) generator.Target {
+	return generator.SimpleTarget{
+		PkgName:       "foo",
+		PkgPath:       "example.com/foo",
+		PkgDir:        dir,
+		HeaderComment: []byte(testHeader),
+		GeneratorsFunc: func(*generator.Context) []generator.Generator {
+			gens := []generator.Generator{}
+			for _, f := range files {
+				gens = append(gens, generator.GoGenerator{
+					OutputFilename: f,
+					OptionalBody:   []byte("var _ = 1\n"),
+				})
+			}
+			return gens
+		},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	tgt := newTestTarget(dir, "a.go", "b.go", "c.go")
+
+	c := newTestContext()
+	c.GeneratedBy = testGeneratedBy
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Verify = true
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", testHeader+"package foo\n\nvar _ = 2\n")
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	write("d.go", testHeader+"package foo\n")
+	write("handwritten.go", "package foo\n")
+
+	err := c.ExecuteTargets([]generator.Target{tgt})
+	var verifyErr *generator.VerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("expected a VerifyError, got %v", err)
+	}
+	cases := map[generator.MismatchKind][]string{
+		generator.StaleFile:   {filepath.Join(dir, "a.go")},
+		generator.MissingFile: {filepath.Join(dir, "b.go")},
+		generator.ExtraFile:   {filepath.Join(dir, "d.go")},
+	}
+	for kind, want := range cases {
+		if got := verifyErr.Paths(kind); !reflect.DeepEqual(want, got) {
+			t.Errorf("wrong %s files:\nwant: %v\ngot:  %v", kind, want, got)
+		}
+	}
+
+	// Nothing should have been written.
+	if _, err := os.Stat(filepath.Join(dir, "b.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected b.go to not exist, got %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "a.go")); err != nil {
+		t.Fatal(err)
+	} else if want, got := testHeader+"package foo\n\nvar _ = 2\n", string(b); want != got {
+		t.Errorf("expected a.go to be unchanged, got %q", got)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	var targets []generator.Target
+	for i := 0; i < 20; i++ {
+		targets = append(targets, newTestTarget(filepath.Join("out", fmt.Sprintf("pkg%02d", i)), "a.go", "b.go"))
+	}
+	// Add some failing targets, to check that errors are reported in order.
+	for _, i := range []int{15, 3, 9} {
+		targets[i] = generator.SimpleTarget{
+			PkgName: "foo",
+			PkgPath: fmt.Sprintf("example.com/bad%02d", i),
+			PkgDir:  filepath.Join("out", fmt.Sprintf("bad%02d", i)),
+			GeneratorsFunc: func(*generator.Context) []generator.Generator {
+				return []generator.Generator{badFileTypeGenerator{generator.GoGenerator{OutputFilename: "a.go"}}}
+			},
+		}
+	}
+	// And a generator which uses the namers, to exercise their caches.
+	for i := range targets {
+		if tgt, ok := targets[i].(generator.SimpleTarget); ok && tgt.HeaderComment != nil {
+			tgt.GeneratorsFunc = func(*generator.Context) []generator.Generator {
+				return []generator.Generator{namingGenerator{generator.GoGenerator{OutputFilename: "names.go"}}}
+			}
+			targets[i] = tgt
+		}
+	}
+	u := types.Universe{}
+	var order []*types.Type
+	for i := 0; i < 50; i++ {
+		order = append(order, u.Type(types.Name{Package: "example.com/foo", Name: fmt.Sprintf("T%d", i)}))
+	}
+
+	run := func(parallelism int) (*generator.MemoryFileSystem, error) {
+		fsys := generator.NewMemoryFileSystem()
+		c := newTestContext()
+		c.Namers = namer.NameSystems{"public": namer.NewPublicNamer(0)}
+		c.Universe = u
+		c.Order = order
+		c.OutputFS = fsys
+		c.Parallelism = parallelism
+		return fsys, c.ExecuteTargets(targets)
+	}
+	serialFS, serialErr := run(0)
+	if serialErr == nil {
+		t.Fatalf("expected an error")
+	}
+	for _, parallelism := range []int{2, 8, 100} {
+		fsys, err := run(parallelism)
+		if err == nil || err.Error() != serialErr.Error() {
+			t.Errorf("parallelism %d: wrong error:\nwant: %v\ngot:  %v", parallelism, serialErr, err)
+		}
+		if want, got := serialFS.Paths(), fsys.Paths(); !reflect.DeepEqual(want, got) {
+			t.Errorf("parallelism %d: wrong files:\nwant: %v\ngot:  %v", parallelism, want, got)
+		}
+		for _, path := range serialFS.Paths() {
+			want, _ := serialFS.ReadFile(path)
+			if got, err := fsys.ReadFile(path); err != nil || !bytes.Equal(want, got) {
+				t.Errorf("parallelism %d: wrong content for %s (%v):\nwant: %q\ngot:  %q", parallelism, path, err, want, got)
+			}
+		}
+	}
+}
+
+type badFileTypeGenerator struct {
+	generator.GoGenerator
+}
+
+func (badFileTypeGenerator) FileType() string { return "nope" }
+
+type namingGenerator struct {
+	generator.GoGenerator
+}
+
+func (namingGenerator) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "// %s\n", c.Namers["public"].Name(t))
+	return err
+}
+
+func TestFileImportTracker(t *testing.T) {
+	u := types.Universe{}
+	foo := u.Type(types.Name{Package: "example.com/a/v1", Name: "Foo"})
+	bar := u.Type(types.Name{Package: "example.com/b/v1", Name: "Bar"})
+
+	fsys := generator.NewMemoryFileSystem()
+	c := newTestContext()
+	c.OutputFS = fsys
+	c.Universe = u
+	c.Order = []*types.Type{bar, foo}
+
+	gen := func(name string, want *types.Type) generator.Generator {
+		return importingGenerator{GoGenerator: generator.GoGenerator{OutputFilename: "a.go"}, name: name, want: want}
+	}
+	tgt := generator.SimpleTarget{
+		PkgName:       "foo",
+		PkgPath:       "example.com/foo",
+		PkgDir:        "out",
+		HeaderComment: []byte(testHeader),
+		GeneratorsFunc: func(*generator.Context) []generator.Generator {
+			// Each generator names a type from a different "v1" package,
+			// which must get different import names.
+			return []generator.Generator{gen("x", bar), gen("y", foo)}
+		},
+	}
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := fsys.ReadFile(filepath.Join("out", "a.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := testHeader + `package foo
+
+import (
+	av1 "example.com/a/v1"
+	v1 "example.com/b/v1"
+)
+
+var x v1.Bar
+
+var y av1.Foo
+`
+	if want := expected; want != string(got) {
+		t.Errorf("wrong output:\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// importingGenerator declares a variable of one type, relying on the file's
+// import tracker for the import.
+type importingGenerator struct {
+	generator.GoGenerator
+	name string
+	want *types.Type
+}
+
+func (g importingGenerator) Namers(c *generator.Context) namer.NameSystems {
+	return namer.NameSystems{"raw": namer.NewRawNamer("example.com/foo", c.ImportTracker)}
+}
+
+func (g importingGenerator) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	if t != g.want {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "var %s %s\n\n", g.name, c.Namers["raw"].Name(t))
+	return err
+}
+
+func TestCleanup(t *testing.T) {
+	const marker = "// Code generated by test-gen. DO NOT EDIT."
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old.go", marker+"\n\npackage foo\n")
+	write("old_header.go", "// Copyright 1999 Someone.\n\n"+marker+"\n\npackage foo\n")
+	// A file which starts with the target's header, but not the marker, may
+	// have been written by hand.
+	write("same_header.go", testHeader+"package foo\n")
+	write("other_tool.go", "// Code generated by other-gen. DO NOT EDIT.\n\npackage foo\n")
+	write("handwritten.go", "package foo\n\n"+marker+"\n")
+
+	c := newTestContext()
+	c.GeneratedBy = marker
+
+	// Verify mode reports orphans, but does not remove them.
+	c.Verify = true
+	err := c.ExecuteTargets([]generator.Target{newTestTarget(dir, "a.go")})
+	var verifyErr *generator.VerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("expected a VerifyError, got %v", err)
+	}
+	orphans := []string{filepath.Join(dir, "old.go"), filepath.Join(dir, "old_header.go")}
+	if want, got := orphans, verifyErr.Paths(generator.ExtraFile); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong extra files:\nwant: %v\ngot:  %v", want, got)
+	}
+	for _, path := range orphans {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to still exist, got %v", path, err)
+		}
+	}
+
+	// Without Cleanup, nothing is removed.
+	c.Verify = false
+	if err := c.ExecuteTargets([]generator.Target{newTestTarget(dir, "a.go")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, path := range orphans {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to still exist, got %v", path, err)
+		}
+	}
+
+	c.Cleanup = true
+	if err := c.ExecuteTargets([]generator.Target{newTestTarget(dir, "a.go")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.go", "handwritten.go", "other_tool.go", "same_header.go"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("wrong files after cleanup:\nwant: %v\ngot:  %v", want, names)
+	}
+}
+
+func TestFormatFailurePolicy(t *testing.T) {
+	const good = testHeader + "package foo\n\nvar _ = 1\n"
+	const bad = testHeader + "package foo\n\nfunc {\n"
+
+	testCases := []struct {
+		policy       generator.FormatFailurePolicy
+		expectedFile string
+		expectSide   bool
+	}{
+		{policy: generator.WriteUnformatted, expectedFile: bad},
+		{policy: generator.KeepExisting, expectedFile: good},
+		{policy: generator.WriteSidecar, expectedFile: good, expectSide: true},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "a.go")
+			if err := os.WriteFile(path, []byte(good), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ft := generator.NewGoFile()
+			ft.OnFormatFailure = tc.policy
+			c := &generator.Context{FileTypes: map[string]generator.FileType{generator.GoFileType: ft}}
+			tgt := generator.SimpleTarget{
+				PkgName:       "foo",
+				PkgPath:       "example.com/foo",
+				PkgDir:        dir,
+				HeaderComment: []byte(testHeader),
+				GeneratorsFunc: func(*generator.Context) []generator.Generator {
+					return []generator.Generator{generator.GoGenerator{OutputFilename: "a.go", OptionalBody: []byte("func {\n")}}
+				},
+			}
+			if err := c.ExecuteTargets([]generator.Target{tgt}); err == nil {
+				t.Fatalf("expected a format error")
+			}
+			if b, err := os.ReadFile(path); err != nil {
+				t.Fatal(err)
+			} else if want, got := tc.expectedFile, string(b); want != got {
+				t.Errorf("wrong content:\nwant: %q\ngot:  %q", want, got)
+			}
+			sidecar := path + generator.FailedFileSuffix
+			b, err := os.ReadFile(sidecar)
+			if !tc.expectSide {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("expected no sidecar file, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			} else if want, got := bad, string(b); want != got {
+				t.Errorf("wrong sidecar content:\nwant: %q\ngot:  %q", want, got)
+			}
+
+			// A successful write removes the sidecar.
+			if err := c.ExecuteTargets([]generator.Target{newTestTarget(dir, "a.go")}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := os.Stat(sidecar); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected sidecar to be removed, got %v", err)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package generator
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 
 	"k8s.io/gengo/v2/namer"
@@ -65,12 +66,39 @@ type File struct {
 	Vars        bytes.Buffer
 	Consts      bytes.Buffer
 	Body        bytes.Buffer
+
+	// Shared by all of the generators which write to this file.
+	importTracker *namer.DefaultImportTracker
+	// The names of the generators which write to this file, and of the
+	// types they were given, for the Manifest.
+	generators []string
+	inputTypes map[string]bool
 }
 
+// FileType knows how to turn a File into its final form and write it out.
 type FileType interface {
 	AssembleFile(f *File, path string) error
 }
 
+// FSFileType is a FileType which can write to any OutputFS (see
+// Context.OutputFS). Plain FileTypes write to the real filesystem; when
+// Context.OutputFS is set, their output goes through a temporary file.
+type FSFileType interface {
+	FileType
+	// AssembleFileTo writes f to path in fsys.
+	AssembleFileTo(fsys OutputFS, f *File, path string) error
+}
+
+// VerifyingFileType is a FileType which can compare a File against what was
+// previously written (see Context.Verify). Plain FileTypes are verified by
+// assembling the File into a temporary file and comparing that.
+type VerifyingFileType interface {
+	FileType
+	// VerifyFile compares f against what is at path in fsys, without writing
+	// anything. Differences are reported as a *MismatchError.
+	VerifyFile(fsys OutputFS, f *File, path string) error
+}
+
 // Generator is the contract for anything that wants to do auto-generation.
 // It's expected that the io.Writers passed to the below functions will be
 // ErrorTrackers; this allows implementations to not check for io errors,
@@ -136,13 +164,16 @@ type Generator interface {
 	// imports in the format `name "path/to/pkg"`. Imports will be called
 	// after Init, PackageVars, PackageConsts, and GenerateType, to allow
 	// you to keep track of what imports you actually need.
+	//
+	// Anything tracked by the Context's ImportTracker is imported
+	// automatically, so generators which use it need not return anything
+	// here.
 	Imports(*Context) []string
 
 	// Preferred file name of this generator, not including a path. It is
-	// allowed for multiple generators to use the same filename, but it's
-	// up to you to make sure they don't have colliding import names.
-	// TODO: provide per-file import tracking, removing the requirement
-	// that generators coordinate..
+	// allowed for multiple generators to use the same filename. Generators
+	// which share a file also share the Context's ImportTracker, so if they
+	// all use it for naming, their import names will not collide.
 	Filename() string
 
 	// A registered file type in the context to generate this file with. If
@@ -151,6 +182,9 @@ type Generator interface {
 }
 
 // Context is global context for individual generators to consume.
+//
+// The fields which control execution, from Verify to Parallelism, are not
+// set by NewContext; you may set them on the Context it returns.
 type Context struct {
 	// A map from the naming system to the names for that system. E.g., you
 	// might have public names and several private naming systems.
@@ -170,8 +204,87 @@ type Context struct {
 	// the default "go" filetype will be provided.
 	FileTypes map[string]FileType
 
+	// If true, Execute* calls will just verify that the existing output is
+	// correct, and will not write anything. Any differences are reported as
+	// a *VerifyError.
+	Verify bool
+
+	// If true, files in each target's directory which were generated by the
+	// running tool, but which were not generated in this run, are removed.
+	// In verify mode they are reported as ExtraFile mismatches instead
+	// (whether or not this is set). A file was generated by the running
+	// tool if it has the GeneratedBy line before its package clause, so if
+	// GeneratedBy is empty, no files are removed. Sidecar files from the
+	// WriteSidecar policy are left alone.
+	Cleanup bool
+
+	// The "generated by" line which marks files written by the running
+	// tool, e.g. "// Code generated by deepcopy-gen. DO NOT EDIT." (see
+	// gengo.GeneratedByLine). This is how Cleanup and verify mode recognize
+	// files which the tool owns.
+	GeneratedBy string
+
+	// If not nil, targets whose inputs have not changed since they were last
+	// executed, and whose output is unchanged, are skipped. This is not used
+	// in verify mode. See ExecutionCache for what counts as an input.
+	Cache *ExecutionCache
+
+	// If not nil, orphaned files (see Cleanup) are neither removed nor
+	// reported as mismatches when each target is executed. Instead, they
+	// are collected here, along with the files which were generated, so
+	// that several Contexts which generate into the same directories can
+	// handle them once all have been executed (see gengo.ExecutePlugins).
+	Orphans *OrphanSet
+
+	// If not nil, every file which is written is recorded here. This is
+	// not used in verify mode.
+	Manifest *Manifest
+
+	// The filesystem to which output is written. If nil, the real
+	// filesystem is used.
+	OutputFS OutputFS
+
+	// The maximum number of targets which ExecuteTargets will execute at
+	// once. If this is less than 2, targets are executed one at a time.
+	//
+	// When targets are executed in parallel, each worker gets its own copy
+	// of the Context, with Namers cloned by NameSystems.Clone, so name caches
+	// are not shared between workers. Everything else is shared, and must be
+	// safe for concurrent use: Targets and Generators, any Namers which
+	// NameSystems.Clone does not copy, the ImportTrackers of raw namers in
+	// Namers (which the clones share; DefaultImportTracker is not safe for
+	// concurrent use, so those raw namers should not have one), FileTypes and
+	// OutputFS. The Universe is shared as well, and must be treated as
+	// read-only: LoadPackages will fail, and generators must not look up
+	// types which are not already in the Universe, nor call Universe.Instance
+	// or Universe.Instantiate (since those add them).
+	//
+	// Errors and mismatches are reported in the same order as when the
+	// targets are executed one at a time.
+	Parallelism int
+
+	// The import tracker for the file which the current generator is
+	// writing, which is shared by every generator writing to that file.
+	// Everything it tracks is imported by the file. Pass it to a raw namer
+	// (see namer.NewRawNamer) in Generator.Namers, and that namer will
+	// assign import names which are consistent across all of the file's
+	// generators. This is nil except when calling a Generator's methods.
+	ImportTracker *namer.DefaultImportTracker
+
 	// Allows generators to add packages at runtime.
 	parser *parser.Parser
+
+	// True if this context is being used by one of several concurrent
+	// workers.
+	parallel bool
+}
+
+// outputFS returns the filesystem to which output should be written.
+func (c *Context) outputFS() OutputFS {
+	if c.OutputFS == nil {
+		return OSFileSystem{}
+	}
+	return c.OutputFS
 }
 
 // NewContext generates a context from the given parser, naming systems, and
@@ -181,11 +294,18 @@ func NewContext(p *parser.Parser, nameSystems namer.NameSystems, canonicalOrderN
 	if err != nil {
 		return nil, err
 	}
+	return NewContextFromUniverse(p, universe, p.UserRequestedPackages(), nameSystems, canonicalOrderName), nil
+}
 
+// NewContextFromUniverse is like NewContext, but for a Universe which was
+// already made by the parser, so that several contexts (e.g. for different
+// generators) can share one parse. Inputs are the packages the context is
+// for, which should be among those the parser loaded.
+func NewContextFromUniverse(p *parser.Parser, universe types.Universe, inputs []string, nameSystems namer.NameSystems, canonicalOrderName string) *Context {
 	c := &Context{
 		Namers:   namer.NameSystems{},
 		Universe: universe,
-		Inputs:   p.UserRequestedPackages(),
+		Inputs:   inputs,
 		FileTypes: map[string]FileType{
 			GoFileType: NewGoFile(),
 		},
@@ -199,11 +319,14 @@ func NewContext(p *parser.Parser, nameSystems namer.NameSystems, canonicalOrderN
 			c.Order = orderer.OrderUniverse(universe)
 		}
 	}
-	return c, nil
+	return c
 }
 
 // LoadPackages adds Go packages to the context.
 func (c *Context) LoadPackages(patterns ...string) ([]*types.Package, error) {
+	if c.parallel {
+		return nil, fmt.Errorf("can't load packages while executing targets in parallel")
+	}
 	return c.parser.LoadPackagesTo(&c.Universe, patterns...)
 }
 
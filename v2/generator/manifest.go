@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"sync"
+
+	"k8s.io/gengo/v2/internal/version"
+)
+
+// Manifest describes every file written by ExecuteTargets (see
+// Context.Manifest), so that other tools can tell where each file came from.
+//
+// A Manifest is safe for concurrent use.
+type Manifest struct {
+	lock  sync.Mutex
+	files map[string]ManifestFile
+}
+
+// ManifestFile describes one generated file.
+type ManifestFile struct {
+	// Path is the location of the file.
+	Path string `json:"path"`
+	// Target is the import path of the target which the file belongs to.
+	Target string `json:"target"`
+	// Generators lists the names of the generators which wrote to the file.
+	Generators []string `json:"generators"`
+	// InputTypes lists the names of the types which were passed to those
+	// generators, sorted.
+	InputTypes []string `json:"inputTypes"`
+	// SHA256 is the hex-encoded SHA-256 hash of the file's content.
+	SHA256 string `json:"sha256"`
+	// GengoVersion is the version of gengo which generated the file, if
+	// known.
+	GengoVersion string `json:"gengoVersion,omitempty"`
+}
+
+// NewManifest returns an empty Manifest.
+func NewManifest() *Manifest {
+	return &Manifest{files: map[string]ManifestFile{}}
+}
+
+// add records files, replacing any earlier records with the same paths.
+func (m *Manifest) add(files ...ManifestFile) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, f := range files {
+		m.files[f.Path] = f
+	}
+}
+
+// Files returns the recorded files, sorted by path.
+func (m *Manifest) Files() []ManifestFile {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	out := make([]ManifestFile, 0, len(m.files))
+	for _, f := range m.files {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// MarshalJSON encodes the manifest as a JSON object with a "files" list,
+// sorted by path.
+func (m *Manifest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Files []ManifestFile `json:"files"`
+	}{m.Files()})
+}
+
+// manifestFile builds the manifest record for f, which was written to path
+// with the specified content.
+func manifestFile(f *File, path string, content []byte) ManifestFile {
+	inputTypes := make([]string, 0, len(f.inputTypes))
+	for name := range f.inputTypes {
+		inputTypes = append(inputTypes, name)
+	}
+	sort.Strings(inputTypes)
+	return ManifestFile{
+		Path:         path,
+		Target:       f.PackagePath,
+		Generators:   slices.Clone(f.generators),
+		InputTypes:   inputTypes,
+		SHA256:       hashBytes(content),
+		GengoVersion: GengoVersion(),
+	}
+}
+
+// GengoVersion returns the version of the gengo module which is linked into
+// the running binary, or "" if it is not known.
+func GengoVersion() string {
+	return version.Gengo()
+}
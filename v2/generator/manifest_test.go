@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/gengo/v2/generator"
+	"k8s.io/gengo/v2/types"
+)
+
+func TestManifest(t *testing.T) {
+	u := types.Universe{}
+	foo := u.Type(types.Name{Package: "example.com/in", Name: "Foo"})
+	bar := u.Type(types.Name{Package: "example.com/in", Name: "Bar"})
+
+	fsys := generator.NewMemoryFileSystem()
+	c := newTestContext()
+	c.Universe = u
+	c.Order = []*types.Type{bar, foo}
+	c.OutputFS = fsys
+	c.Manifest = generator.NewManifest()
+
+	onlyFoo := func(_ *generator.Context, t *types.Type) bool { return t == foo }
+	tgt := generator.SimpleTarget{
+		PkgName:       "foo",
+		PkgPath:       "example.com/foo",
+		PkgDir:        "out",
+		HeaderComment: []byte(testHeader),
+		GeneratorsFunc: func(*generator.Context) []generator.Generator {
+			return []generator.Generator{
+				generator.GoGenerator{OutputFilename: "a.go"},
+				filteringGenerator{generator.GoGenerator{OutputFilename: "b.go"}, onlyFoo},
+				// Different name, same file.
+				namedGenerator{filteringGenerator{generator.GoGenerator{OutputFilename: "b.go"}, onlyFoo}, "other"},
+			}
+		},
+	}
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hashOf := func(path string) string {
+		t.Helper()
+		b, err := fsys.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:])
+	}
+	aPath, bPath := filepath.Join("out", "a.go"), filepath.Join("out", "b.go")
+	version := generator.GengoVersion()
+	expected := []generator.ManifestFile{{
+		Path:         aPath,
+		Target:       "example.com/foo",
+		Generators:   []string{"a.go"},
+		InputTypes:   []string{"example.com/in.Bar", "example.com/in.Foo"},
+		SHA256:       hashOf(aPath),
+		GengoVersion: version,
+	}, {
+		Path:         bPath,
+		Target:       "example.com/foo",
+		Generators:   []string{"b.go", "other"},
+		InputTypes:   []string{"example.com/in.Foo"},
+		SHA256:       hashOf(bPath),
+		GengoVersion: version,
+	}}
+	if got := c.Manifest.Files(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("wrong manifest:\nwant: %+v\ngot:  %+v", expected, got)
+	}
+
+	b, err := json.Marshal(c.Manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded := struct{ Files []generator.ManifestFile }{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, decoded.Files) {
+		t.Errorf("wrong JSON manifest:\nwant: %+v\ngot:  %s", expected, b)
+	}
+
+	// Targets skipped by the cache are still described.
+	c.Cache = generator.NewExecutionCache("")
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Manifest = generator.NewManifest()
+	c.OutputFS = fsys
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.Manifest.Files(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("wrong manifest after skipping:\nwant: %+v\ngot:  %+v", expected, got)
+	}
+}
+
+type filteringGenerator struct {
+	generator.GoGenerator
+	filter func(*generator.Context, *types.Type) bool
+}
+
+func (g filteringGenerator) Filter(c *generator.Context, t *types.Type) bool { return g.filter(c, t) }
+
+type namedGenerator struct {
+	generator.Generator
+	name string
+}
+
+func (g namedGenerator) Name() string { return g.name }
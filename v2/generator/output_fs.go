@@ -0,0 +1,352 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutputFS is the filesystem into which generated files are written, and
+// from which existing files are read (e.g. when verifying). Paths are
+// OS-style paths, as returned by Target.Dir, joined with file names.
+//
+// Implementations must be safe for concurrent use.
+type OutputFS interface {
+	// ReadFile returns the content of the named file. If the file does not
+	// exist, the error satisfies errors.Is(err, fs.ErrNotExist).
+	ReadFile(name string) ([]byte, error)
+
+	// WriteFile writes the named file, creating or replacing it as needed.
+	// Readers should never see a partially written file.
+	WriteFile(name string, data []byte) error
+
+	// MkdirAll creates the named directory, along with any necessary
+	// parents. It is not an error if the directory already exists.
+	MkdirAll(path string) error
+
+	// ReadDir returns the entries of the named directory, sorted by name.
+	// If the directory does not exist, the error satisfies
+	// errors.Is(err, fs.ErrNotExist).
+	ReadDir(name string) ([]fs.DirEntry, error)
+
+	// Remove removes the named file.
+	Remove(name string) error
+}
+
+// OSFileSystem is an OutputFS which operates on the real filesystem.
+type OSFileSystem struct{}
+
+var _ OutputFS = OSFileSystem{}
+
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile writes data to a temporary file in the same directory, and then
+// renames it into place, so a failure part-way through never leaves a
+// partially written file behind. If the file already exists, its permissions
+// are kept.
+func (OSFileSystem) WriteFile(name string, data []byte) (err error) {
+	perm := fs.FileMode(0644)
+	if fi, err := os.Stat(name); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (OSFileSystem) MkdirAll(path string) error {
+	return os.MkdirAll(path, 0755)
+}
+
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// MemoryFileSystem is an OutputFS which holds all files in memory. It is
+// useful for tests, or for tools which want to post-process the output
+// (e.g. into an archive) rather than writing it to disk.
+type MemoryFileSystem struct {
+	lock  sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+var _ OutputFS = &MemoryFileSystem{}
+
+// NewMemoryFileSystem returns a new, empty MemoryFileSystem.
+func NewMemoryFileSystem() *MemoryFileSystem {
+	return &MemoryFileSystem{
+		files: map[string][]byte{},
+		dirs:  map[string]bool{},
+	}
+}
+
+func (m *MemoryFileSystem) ReadFile(name string) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	data, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(data), nil
+}
+
+func (m *MemoryFileSystem) WriteFile(name string, data []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name = filepath.Clean(name)
+	if m.dirs[name] {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	m.mkdirAll(filepath.Dir(name))
+	m.files[name] = slices.Clone(data)
+	return nil
+}
+
+func (m *MemoryFileSystem) MkdirAll(path string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	path = filepath.Clean(path)
+	if _, isFile := m.files[path]; isFile {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+	}
+	m.mkdirAll(path)
+	return nil
+}
+
+// mkdirAll must be called with the lock held.
+func (m *MemoryFileSystem) mkdirAll(path string) {
+	for !m.dirs[path] {
+		m.dirs[path] = true
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+}
+
+func (m *MemoryFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name = filepath.Clean(name)
+	if !m.dirs[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := map[string]fs.DirEntry{}
+	for path, data := range m.files {
+		if filepath.Dir(path) == name {
+			base := filepath.Base(path)
+			entries[base] = memEntry{name: base, size: int64(len(data))}
+		}
+	}
+	for path := range m.dirs {
+		if path != name && filepath.Dir(path) == name {
+			base := filepath.Base(path)
+			entries[base] = memEntry{name: base, dir: true}
+		}
+	}
+	return sortedEntries(entries), nil
+}
+
+func (m *MemoryFileSystem) Remove(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if m.dirs[name] {
+		for path := range m.files {
+			if strings.HasPrefix(path, name+string(filepath.Separator)) {
+				return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+			}
+		}
+		delete(m.dirs, name)
+		return nil
+	}
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
+
+// Paths returns the paths of all files in the filesystem, sorted.
+func (m *MemoryFileSystem) Paths() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return slices.Sorted(maps.Keys(m.files))
+}
+
+// OverlayFileSystem is an OutputFS which reads through to an underlying
+// OutputFS, but keeps all writes and removals in memory. It is useful for
+// running generators against an existing tree (e.g. to see what they would
+// change) without modifying it.
+type OverlayFileSystem struct {
+	lower OutputFS
+	upper *MemoryFileSystem
+
+	lock    sync.Mutex
+	removed map[string]bool
+}
+
+var _ OutputFS = &OverlayFileSystem{}
+
+// NewOverlayFileSystem returns a new OverlayFileSystem on top of lower.
+func NewOverlayFileSystem(lower OutputFS) *OverlayFileSystem {
+	return &OverlayFileSystem{
+		lower:   lower,
+		upper:   NewMemoryFileSystem(),
+		removed: map[string]bool{},
+	}
+}
+
+func (o *OverlayFileSystem) isRemoved(name string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.removed[filepath.Clean(name)]
+}
+
+func (o *OverlayFileSystem) ReadFile(name string) ([]byte, error) {
+	if data, err := o.upper.ReadFile(name); err == nil {
+		return data, nil
+	}
+	if o.isRemoved(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return o.lower.ReadFile(name)
+}
+
+func (o *OverlayFileSystem) WriteFile(name string, data []byte) error {
+	if err := o.upper.WriteFile(name, data); err != nil {
+		return err
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.removed, filepath.Clean(name))
+	return nil
+}
+
+func (o *OverlayFileSystem) MkdirAll(path string) error {
+	return o.upper.MkdirAll(path)
+}
+
+func (o *OverlayFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries := map[string]fs.DirEntry{}
+	lower, lowerErr := o.lower.ReadDir(name)
+	for _, e := range lower {
+		if !o.isRemoved(filepath.Join(name, e.Name())) {
+			entries[e.Name()] = e
+		}
+	}
+	upper, upperErr := o.upper.ReadDir(name)
+	for _, e := range upper {
+		entries[e.Name()] = e
+	}
+	if lowerErr != nil && upperErr != nil {
+		return nil, lowerErr
+	}
+	return sortedEntries(entries), nil
+}
+
+func (o *OverlayFileSystem) Remove(name string) error {
+	upperErr := o.upper.Remove(name)
+	if _, err := o.ReadFile(name); upperErr != nil && err != nil {
+		return upperErr
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.removed[filepath.Clean(name)] = true
+	return nil
+}
+
+// Changes returns the files which have been written to the overlay, as a
+// MemoryFileSystem, and the paths of all files which have been removed,
+// sorted.
+func (o *OverlayFileSystem) Changes() (*MemoryFileSystem, []string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.upper, slices.Sorted(maps.Keys(o.removed))
+}
+
+func sortedEntries(entries map[string]fs.DirEntry) []fs.DirEntry {
+	out := make([]fs.DirEntry, 0, len(entries))
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		out = append(out, entries[name])
+	}
+	return out
+}
+
+// memEntry implements fs.DirEntry and fs.FileInfo for MemoryFileSystem.
+type memEntry struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (e memEntry) Name() string               { return e.name }
+func (e memEntry) IsDir() bool                { return e.dir }
+func (e memEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e memEntry) Size() int64                { return e.size }
+func (e memEntry) ModTime() time.Time         { return time.Time{} }
+func (e memEntry) Sys() any                   { return nil }
+
+func (e memEntry) Type() fs.FileMode {
+	return e.Mode().Type()
+}
+
+func (e memEntry) Mode() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/gengo/v2/generator"
+)
+
+const testBody = testHeader + "package foo\n\nvar _ = 1\n"
+
+func TestMemoryFileSystem(t *testing.T) {
+	fsys := generator.NewMemoryFileSystem()
+	c := newTestContext()
+	c.OutputFS = fsys
+	tgt := newTestTarget(filepath.Join("out", "foo"), "a.go", "b.go")
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{filepath.Join("out", "foo", "a.go"), filepath.Join("out", "foo", "b.go")}
+	if got := fsys.Paths(); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong files:\nwant: %v\ngot:  %v", want, got)
+	}
+	for _, path := range want {
+		if b, err := fsys.ReadFile(path); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if got := string(b); got != testBody {
+			t.Errorf("wrong content for %s:\nwant: %q\ngot:  %q", path, testBody, got)
+		}
+	}
+	if _, err := os.Stat("out"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected nothing to be written to disk, got %v", err)
+	}
+
+	entries, err := fsys.ReadDir("out")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "foo" || !entries[0].IsDir() {
+		t.Errorf("expected a single directory entry, got %v", entries)
+	}
+
+	c.Verify = true
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Errorf("unexpected verify error: %v", err)
+	}
+}
+
+// plainFileType is a FileType which only knows how to write to disk.
+type plainFileType struct{}
+
+func (plainFileType) AssembleFile(f *generator.File, path string) error {
+	return os.WriteFile(path, append(f.Header, "package "+f.PackageName+"\n"...), 0644)
+}
+
+func TestPlainFileType(t *testing.T) {
+	const want = testHeader + "package foo\n"
+	c := &generator.Context{FileTypes: map[string]generator.FileType{generator.GoFileType: plainFileType{}}}
+
+	// On disk, the FileType writes the file itself.
+	dir := t.TempDir()
+	tgt := newTestTarget(dir, "a.go")
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "a.go")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if got := string(b); got != want {
+		t.Errorf("wrong content:\nwant: %q\ngot:  %q", want, got)
+	}
+
+	// Elsewhere, its output is copied.
+	fsys := generator.NewMemoryFileSystem()
+	c.OutputFS = fsys
+	tgt = newTestTarget("out", "a.go")
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, err := fsys.ReadFile(filepath.Join("out", "a.go")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if got := string(b); got != want {
+		t.Errorf("wrong content:\nwant: %q\ngot:  %q", want, got)
+	}
+	if _, err := os.Stat("out"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected nothing to be written to disk, got %v", err)
+	}
+
+	// And it can be verified.
+	c.Verify = true
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Errorf("unexpected verify error: %v", err)
+	}
+	if err := fsys.WriteFile(filepath.Join("out", "a.go"), []byte("stale")); err != nil {
+		t.Fatal(err)
+	}
+	var verifyErr *generator.VerifyError
+	if err := c.ExecuteTargets([]generator.Target{tgt}); !errors.As(err, &verifyErr) {
+		t.Errorf("expected a verify error, got %v", err)
+	} else if want, got := []string{filepath.Join("out", "a.go")}, verifyErr.Paths(generator.StaleFile); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong stale files: want %q, got %q", want, got)
+	}
+}
+
+func TestOverlayFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	stale := testHeader + "package foo\n\nvar _ = 2\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "old.go"), []byte(testHeader+"package foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlay := generator.NewOverlayFileSystem(generator.OSFileSystem{})
+	c := newTestContext()
+	c.OutputFS = overlay
+	tgt := newTestTarget(dir, "a.go", "b.go")
+	if err := c.ExecuteTargets([]generator.Target{tgt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := overlay.Remove(filepath.Join(dir, "old.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The overlay sees the new content...
+	for _, name := range []string{"a.go", "b.go"} {
+		if b, err := overlay.ReadFile(filepath.Join(dir, name)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if got := string(b); got != testBody {
+			t.Errorf("wrong content for %s:\nwant: %q\ngot:  %q", name, testBody, got)
+		}
+	}
+	if _, err := overlay.ReadFile(filepath.Join(dir, "old.go")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected old.go to be removed, got %v", err)
+	}
+	entries, err := overlay.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.go", "b.go"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("wrong entries:\nwant: %v\ngot:  %v", want, names)
+	}
+
+	// ...but the disk is untouched.
+	if b, err := os.ReadFile(filepath.Join(dir, "a.go")); err != nil {
+		t.Fatal(err)
+	} else if got := string(b); got != stale {
+		t.Errorf("expected a.go to be unchanged on disk, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.go")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected b.go to not exist on disk, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.go")); err != nil {
+		t.Errorf("expected old.go to still exist on disk, got %v", err)
+	}
+
+	written, removed := overlay.Changes()
+	if want := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")}; !reflect.DeepEqual(want, written.Paths()) {
+		t.Errorf("wrong written files:\nwant: %v\ngot:  %v", want, written.Paths())
+	}
+	if want := []string{filepath.Join(dir, "old.go")}; !reflect.DeepEqual(want, removed) {
+		t.Errorf("wrong removed files:\nwant: %v\ngot:  %v", want, removed)
+	}
+}
+
+func TestOSFileSystemWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	fsys := generator.OSFileSystem{}
+
+	if err := fsys.WriteFile(path, []byte("one")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(path, []byte("two")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if b, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if want, got := "two", string(b); want != got {
+		t.Errorf("wrong content: want %q, got %q", want, got)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if want, got := fs.FileMode(0600), fi.Mode().Perm(); want != got {
+		t.Errorf("expected permissions to be kept: want %v, got %v", want, got)
+	}
+	// No temporary files should be left behind.
+	if entries, err := os.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 {
+		t.Errorf("expected only a.go, got %v", entries)
+	}
+
+	// Writing into a missing directory fails, without leaving anything behind.
+	if err := fsys.WriteFile(filepath.Join(dir, "missing", "b.go"), []byte("x")); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestFormatCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      string
+		expected string
+	}{{
+		name: "composite literals",
+		src: `package foo
+
+var _ = []T{T{1}, T{2}}
+var _ = map[K]V{K{1}: V{2}}
+var _ = []*T{&T{1}}
+var _ = [][]int{[]int{1}}
+var _ = []T{U{1}}
+`,
+		expected: `package foo
+
+var _ = []T{{1}, {2}}
+var _ = map[K]V{{1}: {2}}
+var _ = []*T{{1}}
+var _ = [][]int{{1}}
+var _ = []T{U{1}}
+`,
+	}, {
+		name: "slices",
+		src: `package foo
+
+func f(s, t []int) {
+	_ = s[1:len(s)]
+	_ = s[1:len(t)]
+	_ = s[1:len(s):len(s)]
+}
+`,
+		expected: `package foo
+
+func f(s, t []int) {
+	_ = s[1:]
+	_ = s[1:len(t)]
+	_ = s[1:len(s):len(s)]
+}
+`,
+	}, {
+		name: "ranges",
+		src: `package foo
+
+func f(s []int) {
+	for i, _ := range s {
+		_ = i
+	}
+	for _ = range s {
+	}
+	for _, _ = range s {
+	}
+}
+`,
+		expected: `package foo
+
+func f(s []int) {
+	for i := range s {
+		_ = i
+	}
+	for range s {
+	}
+	for range s {
+	}
+}
+`,
+	}, {
+		name: "empty declarations",
+		src: `package foo
+
+const ()
+
+var (
+	// keep me
+)
+
+type T int
+`,
+		expected: `package foo
+
+var (
+// keep me
+)
+
+type T int
+`,
+	}, {
+		name: "imports",
+		src: `package foo
+
+import (
+	"strings"
+	"bytes"
+)
+
+var _ = bytes.NewBuffer
+var _ = strings.NewReader
+`,
+		expected: `package foo
+
+import (
+	"bytes"
+	"strings"
+)
+
+var _ = bytes.NewBuffer
+var _ = strings.NewReader
+`,
+	}}
+
+	gofmt, _ := exec.LookPath("gofmt")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := formatCode([]byte(tc.src))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want, got := tc.expected, string(out); want != got {
+				t.Errorf("wrong output:\nwant:\n%s\ngot:\n%s", want, got)
+			}
+
+			if gofmt == "" {
+				return
+			}
+			// The same pipeline, but exec'ing gofmt, should agree.
+			src, err := importsWrapper([]byte(tc.src))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cmd := exec.Command(gofmt, "-s")
+			cmd.Stdin = bytes.NewReader(src)
+			gofmtOut, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("gofmt failed: %v", err)
+			}
+			if !bytes.Equal(gofmtOut, out) {
+				t.Errorf("output differs from gofmt -s:\ngofmt:\n%s\nours:\n%s", gofmtOut, out)
+			}
+		})
+	}
+}
+
+func TestFormatCodeError(t *testing.T) {
+	if _, err := formatCode([]byte("package foo\n\nfunc {\n")); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MismatchKind describes how a generated file differs from what is on disk.
+type MismatchKind string
+
+const (
+	// StaleFile means the file exists, but its content differs from the
+	// generated output.
+	StaleFile MismatchKind = "stale"
+	// MissingFile means the file would be generated, but does not exist.
+	MissingFile MismatchKind = "missing"
+	// ExtraFile means the file exists and appears to have been generated for
+	// the target, but was not generated in this run.
+	ExtraFile MismatchKind = "extra"
+)
+
+// MismatchError describes a single file which does not match the generated
+// output. It is returned by VerifyingFileType.VerifyFile.
+type MismatchError struct {
+	// Kind is how the file differs.
+	Kind MismatchKind
+	// Path is the location of the file on disk.
+	Path string
+	// Existing is the content of the file on disk. It is nil if Kind is
+	// MissingFile.
+	Existing []byte
+	// Generated is the freshly generated (and formatted) content. It is nil
+	// if Kind is ExtraFile.
+	Generated []byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s file %q", e.Kind, e.Path)
+}
+
+// VerifyError is returned when executing targets in verify mode (see
+// Context.Verify) and one or more generated files do not match what is on
+// disk. Other errors encountered along the way are reported separately.
+type VerifyError struct {
+	// Mismatches lists every file which does not match, sorted by path.
+	Mismatches []*MismatchError
+}
+
+func (e *VerifyError) Error() string {
+	lines := make([]string, 0, len(e.Mismatches)+1)
+	lines = append(lines, fmt.Sprintf("%d generated file(s) are out of date:", len(e.Mismatches)))
+	for _, m := range e.Mismatches {
+		lines = append(lines, fmt.Sprintf("  %s: %s", m.Kind, m.Path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Paths returns the paths of all mismatched files of the specified kind.
+func (e *VerifyError) Paths(kind MismatchKind) []string {
+	var paths []string
+	for _, m := range e.Mismatches {
+		if m.Kind == kind {
+			paths = append(paths, m.Path)
+		}
+	}
+	return paths
+}
+
+// add records more mismatches, keeping the list sorted.
+func (e *VerifyError) add(mismatches ...*MismatchError) {
+	e.Mismatches = append(e.Mismatches, mismatches...)
+	sort.SliceStable(e.Mismatches, func(i, j int) bool {
+		return e.Mismatches[i].Path < e.Mismatches[j].Path
+	})
+}
@@ -18,6 +18,7 @@ package namer
 
 import (
 	"fmt"
+	"maps"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -112,6 +113,32 @@ type Namer interface {
 // NameSystems is a map of a system name to a namer for that system.
 type NameSystems map[string]Namer
 
+// Clone returns a copy of ns in which each NameStrategy and raw namer has its
+// own cache of names (starting from a copy of the original's). Namers cache
+// names as they go, so they are not safe for concurrent use; Clone lets
+// concurrent users each have their own. Other namers are shared as-is, and
+// must be safe for concurrent use if the clone is to be used concurrently.
+//
+// Note that a raw namer's ImportTracker, if any, is shared by the clone.
+func (ns NameSystems) Clone() NameSystems {
+	out := make(NameSystems, len(ns))
+	for name, n := range ns {
+		switch n := n.(type) {
+		case *NameStrategy:
+			c := *n
+			c.Names = maps.Clone(n.Names)
+			out[name] = &c
+		case *rawNamer:
+			c := *n
+			c.Names = maps.Clone(n.Names)
+			out[name] = &c
+		default:
+			out[name] = n
+		}
+	}
+	return out
+}
+
 // NameStrategy is a general Namer. The easiest way to use it is to copy the
 // Public/PrivateNamer variables, and modify the members you wish to change.
 //
@@ -223,13 +250,22 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 	}
 
 	if t.Name.Package != "" {
-		dirs := append(ns.filterDirs(t.Name.Package), t.Name.Name)
+		typeName := t.Name.Name
+		var typeArgs []string
+		if t.Origin != nil {
+			// An instance of a generic type, e.g. Foo[int] => FooInt.
+			typeName = genericBaseName(t.Origin)
+			for _, arg := range t.TypeArgs {
+				typeArgs = append(typeArgs, ns.removePrefixAndSuffix(ns.Name(arg)))
+			}
+		}
+		dirs := append(ns.filterDirs(t.Name.Package), typeName)
 		i := ns.PrependPackageNames + 1
 		dn := len(dirs)
 		if i > dn {
 			i = dn
 		}
-		name := ns.Join(ns.Prefix, dirs[dn-i:], ns.Suffix)
+		name := ns.Join(ns.Prefix, append(dirs[dn-i:], typeArgs...), ns.Suffix)
 		ns.Names[t] = name
 		return name
 	}
@@ -237,7 +273,7 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 	// Only anonymous types remain.
 	var name string
 	switch t.Kind {
-	case types.Builtin:
+	case types.Builtin, types.TypeParam:
 		name = ns.Join(ns.Prefix, []string{t.Name.Name}, ns.Suffix)
 	case types.Map:
 		name = ns.Join(ns.Prefix, []string{
@@ -269,8 +305,15 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 		}
 		name = ns.Join(ns.Prefix, names, ns.Suffix)
 	case types.Chan:
+		var dir string
+		switch t.ChanDir {
+		case types.SendOnly:
+			dir = "SendOnly"
+		case types.RecvOnly:
+			dir = "RecvOnly"
+		}
 		name = ns.Join(ns.Prefix, []string{
-			"Chan",
+			dir + "Chan",
 			ns.removePrefixAndSuffix(ns.Name(t.Elem)),
 		}, ns.Suffix)
 	case types.Interface:
@@ -299,6 +342,13 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 	return name
 }
 
+// genericBaseName returns the name of a generic type without its type
+// parameters, e.g. Foo for Foo[T].
+func genericBaseName(t *types.Type) string {
+	name, _, _ := strings.Cut(t.Name.Name, "[")
+	return name
+}
+
 // ImportTracker allows a raw namer to keep track of the packages needed for
 // import. You can implement yourself or use the one in the generation package.
 type ImportTracker interface {
@@ -326,19 +376,29 @@ func (r *rawNamer) Name(t *types.Type) string {
 		return name
 	}
 	if t.Name.Package != "" {
+		typeName := t.Name.Name
+		if t.Origin != nil {
+			// An instance of a generic type, whose type arguments need to be
+			// named (and imported) too.
+			var typeArgs []string
+			for _, arg := range t.TypeArgs {
+				typeArgs = append(typeArgs, r.Name(arg))
+			}
+			typeName = genericBaseName(t.Origin) + "[" + strings.Join(typeArgs, ", ") + "]"
+		}
 		var name string
 		if r.tracker != nil {
 			r.tracker.AddType(t)
 			if t.Name.Package == r.pkg {
-				name = t.Name.Name
+				name = typeName
 			} else {
-				name = r.tracker.LocalNameOf(t.Name.Package) + "." + t.Name.Name
+				name = r.tracker.LocalNameOf(t.Name.Package) + "." + typeName
 			}
 		} else {
 			if t.Name.Package == r.pkg {
-				name = t.Name.Name
+				name = typeName
 			} else {
-				name = filepath.Base(t.Name.Package) + "." + t.Name.Name
+				name = filepath.Base(t.Name.Package) + "." + typeName
 			}
 		}
 		r.Names[t] = name
@@ -346,7 +406,7 @@ func (r *rawNamer) Name(t *types.Type) string {
 	}
 	var name string
 	switch t.Kind {
-	case types.Builtin:
+	case types.Builtin, types.TypeParam:
 		name = t.Name.Name
 	case types.Map:
 		name = "map[" + r.Name(t.Key) + "]" + r.Name(t.Elem)
@@ -364,8 +424,19 @@ func (r *rawNamer) Name(t *types.Type) string {
 		}
 		name = "struct{" + strings.Join(elems, "; ") + "}"
 	case types.Chan:
-		// TODO: include directionality
-		name = "chan " + r.Name(t.Elem)
+		elem := r.Name(t.Elem)
+		switch t.ChanDir {
+		case types.SendOnly:
+			name = "chan<- " + elem
+		case types.RecvOnly:
+			name = "<-chan " + elem
+		default:
+			// "chan <-chan T" would be read as "chan<- (chan T)".
+			if t.Elem.Kind == types.Chan && t.Elem.Name.Package == "" && t.Elem.ChanDir == types.RecvOnly {
+				elem = "(" + elem + ")"
+			}
+			name = "chan " + elem
+		}
 	case types.Interface:
 		// TODO: add to name test
 		elems := []string{}
@@ -17,7 +17,9 @@ limitations under the License.
 package namer
 
 import (
+	"path"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"k8s.io/gengo/v2/types"
@@ -103,3 +105,173 @@ func TestNameStrategy(t *testing.T) {
 		t.Errorf("Wanted %#v, got %#v", e, a)
 	}
 }
+
+func TestSourceOrderer(t *testing.T) {
+	u := types.Universe{}
+	declare := func(pkg, name string, order int) *types.Type {
+		typ := u.Type(types.Name{Package: pkg, Name: name})
+		typ.Kind = types.Struct
+		typ.DeclarationOrder = order
+		return typ
+	}
+	declare("foo/bar", "Zed", 1)
+	declare("foo/bar", "Alpha", 3)
+	declare("foo/other", "Other", 1)
+	declare("foo/bar", "Middle", 2)
+	// Not declared by the package, e.g. an instance of a generic type.
+	declare("foo/bar", "List[int]", 0)
+	c := u.Constant(types.Name{Package: "foo/bar", Name: "Const"})
+	c.Kind = types.DeclarationOf
+	c.Underlying = types.Int
+	c.DeclarationOrder = 4
+	slice := u.Type(types.Name{Name: "[]bar.Zed"})
+	slice.Kind = types.Slice
+	slice.Elem = u.Type(types.Name{Package: "foo/bar", Name: "Zed"})
+	u.Type(types.Name{Name: "string"})
+
+	o := SourceOrderer{NewRawNamer("", nil)}
+	var orderedNames []string
+	for _, typ := range o.OrderUniverse(u) {
+		orderedNames = append(orderedNames, typ.Name.String())
+	}
+	expect := []string{"[]bar.Zed", "string", "foo/bar.Zed", "foo/bar.Middle", "foo/bar.Alpha", "foo/bar.Const", "foo/bar.List[int]", "foo/other.Other"}
+	if e, a := expect, orderedNames; !reflect.DeepEqual(e, a) {
+		t.Errorf("Wanted %#v, got %#v", e, a)
+	}
+}
+
+func TestNameSystemsClone(t *testing.T) {
+	u := types.Universe{}
+	foo := u.Type(types.Name{Package: "a/b", Name: "Foo"})
+	bar := u.Type(types.Name{Package: "a/b", Name: "Bar"})
+
+	plural := NewPublicPluralNamer(nil)
+	orig := NameSystems{
+		"public": NewPublicNamer(0),
+		"raw":    NewRawNamer("", nil),
+		"plural": plural,
+	}
+	orig["public"].Name(foo)
+	orig["raw"].Name(foo)
+
+	clone := orig.Clone()
+	if clone["plural"] != plural {
+		t.Errorf("expected other namers to be shared")
+	}
+	for name, n := range clone {
+		if name == "plural" {
+			continue
+		}
+		if n == orig[name] {
+			t.Errorf("%s: expected namer to be copied", name)
+		}
+		if want, got := orig[name].Name(foo), n.Name(foo); want != got {
+			t.Errorf("%s: wrong name: want %q, got %q", name, want, got)
+		}
+		n.Name(bar)
+	}
+
+	// Names added to the clone's caches must not show up in the original's.
+	if _, found := orig["public"].(*NameStrategy).Names[bar]; found {
+		t.Errorf("public: clone's cache is shared with the original")
+	}
+	if _, found := orig["raw"].(*rawNamer).Names[bar]; found {
+		t.Errorf("raw: clone's cache is shared with the original")
+	}
+	if _, found := clone["public"].(*NameStrategy).Names[foo]; !found {
+		t.Errorf("public: expected clone's cache to start from the original's")
+	}
+}
+
+func TestChanNames(t *testing.T) {
+	u := types.Universe{}
+	base := u.Type(types.Name{Package: "foo/bar", Name: "Baz"})
+	base.Kind = types.Struct
+
+	chanOf := func(name string, dir types.ChanDir, elem *types.Type) *types.Type {
+		c := u.Type(types.Name{Name: name})
+		c.Kind = types.Chan
+		c.ChanDir = dir
+		c.Elem = elem
+		return c
+	}
+	both := chanOf("chan bar.Baz", types.SendRecv, base)
+	send := chanOf("chan<- bar.Baz", types.SendOnly, base)
+	recv := chanOf("<-chan bar.Baz", types.RecvOnly, base)
+	chanOfRecv := chanOf("chan (<-chan bar.Baz)", types.SendRecv, recv)
+	sendOfRecv := chanOf("chan<- <-chan bar.Baz", types.SendOnly, recv)
+	recvOfBoth := chanOf("<-chan chan bar.Baz", types.RecvOnly, both)
+
+	for _, tc := range []struct {
+		t      *types.Type
+		raw    string
+		public string
+	}{
+		{both, "chan bar.Baz", "ChanBaz"},
+		{send, "chan<- bar.Baz", "SendOnlyChanBaz"},
+		{recv, "<-chan bar.Baz", "RecvOnlyChanBaz"},
+		{chanOfRecv, "chan (<-chan bar.Baz)", "ChanRecvOnlyChanBaz"},
+		{sendOfRecv, "chan<- <-chan bar.Baz", "SendOnlyChanRecvOnlyChanBaz"},
+		{recvOfBoth, "<-chan chan bar.Baz", "RecvOnlyChanChanBaz"},
+	} {
+		if want, got := tc.raw, NewRawNamer("my/package", nil).Name(tc.t); want != got {
+			t.Errorf("raw name: want %q, got %q", want, got)
+		}
+		if want, got := tc.public, NewPublicNamer(0).Name(tc.t); want != got {
+			t.Errorf("public name: want %q, got %q", want, got)
+		}
+	}
+}
+
+func TestGenericNames(t *testing.T) {
+	u := types.Universe{}
+	typeParam := &types.Type{Name: types.Name{Name: "T"}, Kind: types.TypeParam, Constraint: types.Any}
+	list := u.Type(types.Name{Package: "foo/bar", Name: "List[T]"})
+	list.Kind = types.Struct
+	list.TypeParamList = []*types.Type{typeParam}
+	pair := u.Type(types.Name{Package: "foo/other", Name: "Pair[K,V]"})
+	pair.Kind = types.Struct
+
+	pairOfStringInt := &types.Type{
+		Name:     types.Name{Package: "foo/other", Name: "Pair[string,int]"},
+		Kind:     types.Struct,
+		Origin:   pair,
+		TypeArgs: []*types.Type{types.String, types.Int},
+	}
+	listOfPairs := &types.Type{
+		Name:     types.Name{Package: "foo/bar", Name: "List[foo/other.Pair[string,int]]"},
+		Kind:     types.Struct,
+		Origin:   list,
+		TypeArgs: []*types.Type{pairOfStringInt},
+	}
+
+	for _, tc := range []struct {
+		t      *types.Type
+		raw    string
+		public string
+	}{
+		{typeParam, "T", "T"},
+		{list, "List[T]", "List[T]"},
+		{pairOfStringInt, "other.Pair[string, int]", "PairStringInt"},
+		{listOfPairs, "List[other.Pair[string, int]]", "ListPairStringInt"},
+	} {
+		if want, got := tc.raw, NewRawNamer("foo/bar", nil).Name(tc.t); want != got {
+			t.Errorf("raw name: want %q, got %q", want, got)
+		}
+		if want, got := tc.public, NewPublicNamer(0).Name(tc.t); want != got {
+			t.Errorf("public name: want %q, got %q", want, got)
+		}
+	}
+
+	// The packages of type arguments are imported, too.
+	tracker := NewDefaultImportTracker(types.Name{Package: "some/pkg"})
+	tracker.IsInvalidType = func(*types.Type) bool { return false }
+	tracker.LocalName = func(name types.Name) string { return path.Base(name.Package) }
+	tracker.PrintImport = func(path, name string) string { return name + " " + strconv.Quote(path) }
+	if want, got := "bar.List[other.Pair[string, int]]", NewRawNamer("some/pkg", &tracker).Name(listOfPairs); want != got {
+		t.Errorf("raw name: want %q, got %q", want, got)
+	}
+	if want, got := []string{`bar "foo/bar"`, `other "foo/other"`}, tracker.ImportLines(); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong imports: want %v, got %v", want, got)
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package namer
 
 import (
+	"cmp"
+	"slices"
 	"sort"
+	"strings"
 
 	"k8s.io/gengo/v2/types"
 )
@@ -32,23 +35,31 @@ type Orderer struct {
 func (o *Orderer) OrderUniverse(u types.Universe) []*types.Type {
 	list := tList{
 		namer: o.Namer,
+		types: universeTypes(u),
 	}
+	sort.Sort(list)
+	return list.types
+}
+
+// universeTypes returns every type in the Universe, including Types,
+// Functions, Variables and Constants, in no particular order.
+func universeTypes(u types.Universe) []*types.Type {
+	var out []*types.Type
 	for _, p := range u {
 		for _, t := range p.Types {
-			list.types = append(list.types, t)
+			out = append(out, t)
 		}
 		for _, f := range p.Functions {
-			list.types = append(list.types, f)
+			out = append(out, f)
 		}
 		for _, v := range p.Variables {
-			list.types = append(list.types, v)
+			out = append(out, v)
 		}
 		for _, v := range p.Constants {
-			list.types = append(list.types, v)
+			out = append(out, v)
 		}
 	}
-	sort.Sort(list)
-	return list.types
+	return out
 }
 
 // OrderTypes assigns a name to every type, and returns a list sorted by those
@@ -70,3 +81,48 @@ type tList struct {
 func (t tList) Len() int           { return len(t.types) }
 func (t tList) Less(i, j int) bool { return t.namer.Name(t.types[i]) < t.namer.Name(t.types[j]) }
 func (t tList) Swap(i, j int)      { t.types[i], t.types[j] = t.types[j], t.types[i] }
+
+// SourceOrderer produces an ordering of types by where they are declared in
+// the source, rather than by name: by package path, and then in the order in
+// which the package declares them (see types.Type.DeclarationOrder). Within
+// a package, types which it does not declare, such as instances of generic
+// types, follow those which it does. Builtin and anonymous types have no
+// package, so they come first. Types which are not declared are sorted by the
+// names which the Namer gives them.
+//
+// To execute generators in this order, set the generator Context's Order to
+// the result of OrderUniverse.
+type SourceOrderer struct {
+	Namer
+}
+
+// OrderUniverse returns every type in the Universe, including Types,
+// Functions, Variables and Constants, in source order.
+func (o *SourceOrderer) OrderUniverse(u types.Universe) []*types.Type {
+	return o.OrderTypes(universeTypes(u))
+}
+
+// OrderTypes sorts typeList in source order, and returns it.
+func (o *SourceOrderer) OrderTypes(typeList []*types.Type) []*types.Type {
+	names := map[*types.Type]string{}
+	for _, t := range typeList {
+		if t.DeclarationOrder == 0 {
+			names[t] = o.Name(t)
+		}
+	}
+	slices.SortFunc(typeList, func(a, b *types.Type) int {
+		if c := strings.Compare(a.Name.Package, b.Name.Package); c != 0 {
+			return c
+		}
+		switch {
+		case a.DeclarationOrder == 0 && b.DeclarationOrder == 0:
+			return strings.Compare(names[a], names[b])
+		case a.DeclarationOrder == 0:
+			return 1
+		case b.DeclarationOrder == 0:
+			return -1
+		}
+		return cmp.Compare(a.DeclarationOrder, b.DeclarationOrder)
+	})
+	return typeList
+}
@@ -0,0 +1,555 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"k8s.io/gengo/v2/internal/version"
+	"k8s.io/gengo/v2/types"
+	"k8s.io/klog/v2"
+)
+
+// cacheFormat identifies the encoding of cached Universes, and what the
+// parser records in them. Change it whenever either changes.
+const cacheFormat = "4"
+
+// cacheMaxAge is how long a cached Universe is kept without being used.
+const cacheMaxAge = 7 * 24 * time.Hour
+
+// cacheKeyFor returns the key of the Universe which loading patterns would
+// produce. It is a hash of the contents of every file of the packages and
+// their dependencies, recursively, and of everything else which affects what
+// is parsed. Finding the files is much faster than type-checking them.
+func (p *Parser) cacheKeyFor(patterns []string) (string, error) {
+	cfg := packages.Config{
+		Mode:       packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+		BuildFlags: []string{"-tags", strings.Join(p.buildTags, ",")},
+		Tests:      p.includeTestFiles,
+		Overlay:    p.overlay,
+		Dir:        p.dir,
+		Env:        p.env,
+	}
+	pkgs, err := packages.Load(&cfg, patterns...)
+	if err != nil {
+		return "", fmt.Errorf("error loading packages: %w", err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "format %q\ngengo %q\ngo %q\ntags %q\ntests %t\n",
+		cacheFormat, version.Gengo(), runtime.Version(), p.buildTags, p.includeTestFiles)
+	// The environment (e.g. GODEBUG, GOFLAGS or GOWORK) and the directory can
+	// change what is parsed without changing which files are.
+	fmt.Fprintf(h, "dir %q\n", p.dir)
+	for _, kv := range slices.Sorted(slices.Values(p.env)) {
+		fmt.Fprintf(h, "env %q\n", kv)
+	}
+	for _, pkg := range pkgs {
+		fmt.Fprintf(h, "requested %q\n", pkg.ID)
+	}
+	// Test variants have the same path as the packages they are variants
+	// of, so use the IDs.
+	all := map[string]*packages.Package{}
+	var visit func(pkg *packages.Package)
+	visit = func(pkg *packages.Package) {
+		if all[pkg.ID] != nil {
+			return
+		}
+		all[pkg.ID] = pkg
+		for _, imp := range pkg.Imports {
+			visit(imp)
+		}
+	}
+	for _, pkg := range pkgs {
+		visit(pkg)
+	}
+	for _, id := range slices.Sorted(maps.Keys(all)) {
+		pkg := all[id]
+		fmt.Fprintf(h, "package %q %q\n", id, pkg.PkgPath)
+		for _, file := range slices.Sorted(slices.Values(pkg.GoFiles)) {
+			b, found := p.overlay[file]
+			if !found {
+				if b, err = os.ReadFile(file); err != nil {
+					return "", err
+				}
+			}
+			sum := sha256.Sum256(b)
+			fmt.Fprintf(h, "file %q %s\n", file, hex.EncodeToString(sum[:]))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachePath returns the path of the cache file for key.
+func (p *Parser) cachePath(key string) string {
+	return filepath.Join(p.cacheDir, key+".gob")
+}
+
+// readCache reads the cached Universe for key, if there is one.
+func (p *Parser) readCache(key string) (*cachedUniverse, error) {
+	b, err := os.ReadFile(p.cachePath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	cu := &cachedUniverse{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(cu); err != nil {
+		klog.Warningf("Ignoring unreadable parse cache file %q: %v", p.cachePath(key), err)
+		return nil, nil
+	}
+	// Mark it as used, so that it is not evicted.
+	now := time.Now()
+	if err := os.Chtimes(p.cachePath(key), now, now); err != nil {
+		klog.V(2).Infof("Failed to update the time of parse cache file %q: %v", p.cachePath(key), err)
+	}
+	return cu, nil
+}
+
+// writeCache saves u as the cached Universe for key.
+func (p *Parser) writeCache(key string, u types.Universe) error {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(encodeUniverse(u, p.UserRequestedPackages())); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.cacheDir, 0755); err != nil {
+		return err
+	}
+	// Write it atomically, since other tools may be reading the cache.
+	f, err := os.CreateTemp(p.cacheDir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), p.cachePath(key)); err != nil {
+		return err
+	}
+	return p.evictCache(time.Now().Add(-cacheMaxAge))
+}
+
+// evictCache removes the cached Universes, and any temporary files left
+// behind, which were last used before cutoff, so that the cache does not
+// grow without bound.
+func (p *Parser) evictCache(cutoff time.Time) error {
+	entries, err := os.ReadDir(p.cacheDir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !(strings.HasSuffix(e.Name(), ".gob") || strings.HasSuffix(e.Name(), ".tmp")) {
+			continue
+		}
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Removed by another tool.
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			klog.V(2).Infof("Removing unused parse cache file %q", e.Name())
+			if err := os.Remove(filepath.Join(p.cacheDir, e.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cachedUniverse is the serialized form of a Universe. Types refer to each
+// other by their index in Types, plus one, so that zero is nil.
+type cachedUniverse struct {
+	UserRequested []string
+	Packages      []cachedPackage
+	Types         []cachedType
+}
+
+type cachedPackage struct {
+	Path        string
+	Dir         string
+	GoFiles     []string
+	TestGoFiles []string
+	Name        string
+	DocComments []string
+	Comments    []string
+	Types       map[string]int
+	Functions   map[string]int
+	Variables   map[string]int
+	Constants   map[string]int
+	Instances   map[string]int
+	Imports     []string
+}
+
+type cachedType struct {
+	Name                              types.Name
+	Kind                              types.Kind
+	Position                          token.Position
+	DeclarationOrder                  int
+	CommentLines                      []string
+	CommentLinePositions              []token.Position
+	TrailingCommentLines              []string
+	TrailingCommentLinePositions      []token.Position
+	SecondClosestCommentLines         []string
+	SecondClosestCommentLinePositions []token.Position
+	Members                           []cachedMember
+	TypeParams                        map[string]int
+	TypeParamList                     []int
+	Constraint                        int
+	Origin                            int
+	TypeArgs                          []int
+	Elem                              int
+	Key                               int
+	Underlying                        int
+	Methods                           map[string]int
+	MethodSet                         []cachedMethodSelection
+	PointerMethodSet                  []cachedMethodSelection
+	EmbeddedInterfaces                []int
+	Unions                            [][]cachedUnionTerm
+	Signature                         *cachedSignature
+	ConstValue                        *string
+	ConstDecl                         *types.ConstDecl
+	ChanDir                           types.ChanDir
+	Len                               int64
+}
+
+type cachedMember struct {
+	Name                         string
+	Embedded                     bool
+	Position                     token.Position
+	CommentLines                 []string
+	CommentLinePositions         []token.Position
+	TrailingCommentLines         []string
+	TrailingCommentLinePositions []token.Position
+	Tags                         string
+	Type                         int
+}
+
+type cachedMethodSelection struct {
+	Name         string
+	Method       int
+	ReceiverKind types.ReceiverKind
+	Path         []string
+}
+
+type cachedUnionTerm struct {
+	Tilde bool
+	Type  int
+}
+
+type cachedSignature struct {
+	Receiver     int
+	Parameters   []cachedParamResult
+	Results      []cachedParamResult
+	Variadic     bool
+	TypeParams   []int
+	CommentLines []string
+}
+
+type cachedParamResult struct {
+	Name string
+	Type int
+}
+
+// encodeUniverse flattens u.
+func encodeUniverse(u types.Universe, userRequested []string) *cachedUniverse {
+	cu := &cachedUniverse{UserRequested: userRequested}
+	ids := map[*types.Type]int{}
+	var queue []*types.Type
+	id := func(t *types.Type) int {
+		if t == nil {
+			return 0
+		}
+		if _, found := ids[t]; !found {
+			ids[t] = len(ids) + 1
+			queue = append(queue, t)
+		}
+		return ids[t]
+	}
+	idMap := func(in map[string]*types.Type) map[string]int {
+		if in == nil {
+			return nil
+		}
+		out := map[string]int{}
+		for k, t := range in {
+			out[k] = id(t)
+		}
+		return out
+	}
+	idList := func(in []*types.Type) []int {
+		var out []int
+		for _, t := range in {
+			out = append(out, id(t))
+		}
+		return out
+	}
+	methodSet := func(in []*types.MethodSelection) []cachedMethodSelection {
+		var out []cachedMethodSelection
+		for _, m := range in {
+			out = append(out, cachedMethodSelection{Name: m.Name, Method: id(m.Method), ReceiverKind: m.ReceiverKind, Path: m.Path})
+		}
+		return out
+	}
+	params := func(in []*types.ParamResult) []cachedParamResult {
+		var out []cachedParamResult
+		for _, p := range in {
+			out = append(out, cachedParamResult{Name: p.Name, Type: id(p.Type)})
+		}
+		return out
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(u)) {
+		pkg := u[path]
+		cu.Packages = append(cu.Packages, cachedPackage{
+			Path:        pkg.Path,
+			Dir:         pkg.Dir,
+			GoFiles:     pkg.GoFiles,
+			TestGoFiles: pkg.TestGoFiles,
+			Name:        pkg.Name,
+			DocComments: pkg.DocComments,
+			Comments:    pkg.Comments,
+			Types:       idMap(pkg.Types),
+			Functions:   idMap(pkg.Functions),
+			Variables:   idMap(pkg.Variables),
+			Constants:   idMap(pkg.Constants),
+			Instances:   idMap(pkg.Instances),
+			Imports:     slices.Sorted(maps.Keys(pkg.Imports)),
+		})
+	}
+	// Types are added to the queue as they are first referred to.
+	for i := 0; i < len(queue); i++ {
+		t := queue[i]
+		ct := cachedType{
+			Name:                              t.Name,
+			Kind:                              t.Kind,
+			Position:                          t.Position,
+			DeclarationOrder:                  t.DeclarationOrder,
+			CommentLines:                      t.CommentLines,
+			CommentLinePositions:              t.CommentLinePositions,
+			TrailingCommentLines:              t.TrailingCommentLines,
+			TrailingCommentLinePositions:      t.TrailingCommentLinePositions,
+			SecondClosestCommentLines:         t.SecondClosestCommentLines,
+			SecondClosestCommentLinePositions: t.SecondClosestCommentLinePositions,
+			TypeParams:                        idMap(t.TypeParams),
+			TypeParamList:                     idList(t.TypeParamList),
+			Constraint:                        id(t.Constraint),
+			Origin:                            id(t.Origin),
+			TypeArgs:                          idList(t.TypeArgs),
+			Elem:                              id(t.Elem),
+			Key:                               id(t.Key),
+			Underlying:                        id(t.Underlying),
+			Methods:                           idMap(t.Methods),
+			MethodSet:                         methodSet(t.MethodSet),
+			PointerMethodSet:                  methodSet(t.PointerMethodSet),
+			EmbeddedInterfaces:                idList(t.EmbeddedInterfaces),
+			ConstValue:                        t.ConstValue,
+			ConstDecl:                         t.ConstDecl,
+			ChanDir:                           t.ChanDir,
+			Len:                               t.Len,
+		}
+		for _, m := range t.Members {
+			ct.Members = append(ct.Members, cachedMember{
+				Name:                         m.Name,
+				Embedded:                     m.Embedded,
+				Position:                     m.Position,
+				CommentLines:                 m.CommentLines,
+				CommentLinePositions:         m.CommentLinePositions,
+				TrailingCommentLines:         m.TrailingCommentLines,
+				TrailingCommentLinePositions: m.TrailingCommentLinePositions,
+				Tags:                         m.Tags,
+				Type:                         id(m.Type),
+			})
+		}
+		for _, union := range t.Unions {
+			var terms []cachedUnionTerm
+			for _, term := range union {
+				terms = append(terms, cachedUnionTerm{Tilde: term.Tilde, Type: id(term.Type)})
+			}
+			ct.Unions = append(ct.Unions, terms)
+		}
+		if sig := t.Signature; sig != nil {
+			ct.Signature = &cachedSignature{
+				Receiver:     id(sig.Receiver),
+				Parameters:   params(sig.Parameters),
+				Results:      params(sig.Results),
+				Variadic:     sig.Variadic,
+				TypeParams:   idList(sig.TypeParams),
+				CommentLines: sig.CommentLines,
+			}
+		}
+		cu.Types = append(cu.Types, ct)
+	}
+	return cu
+}
+
+// decodeUniverse rebuilds the Universe which cu was flattened from, without
+// the types' GoTypes.
+func decodeUniverse(cu *cachedUniverse) types.Universe {
+	u := types.Universe{}
+	all := make([]*types.Type, len(cu.Types))
+	for i, ct := range cu.Types {
+		// Builtin types are canonical.
+		if ct.Name.Package == "" {
+			if t := (types.Universe{}).Type(ct.Name); t.Kind != types.Unknown {
+				all[i] = t
+				continue
+			}
+		}
+		all[i] = &types.Type{}
+	}
+	byID := func(id int) *types.Type {
+		if id == 0 {
+			return nil
+		}
+		return all[id-1]
+	}
+	byIDMap := func(in map[string]int) map[string]*types.Type {
+		if in == nil {
+			return nil
+		}
+		out := map[string]*types.Type{}
+		for k, id := range in {
+			out[k] = byID(id)
+		}
+		return out
+	}
+	byIDList := func(in []int) []*types.Type {
+		var out []*types.Type
+		for _, id := range in {
+			out = append(out, byID(id))
+		}
+		return out
+	}
+	methodSet := func(in []cachedMethodSelection) []*types.MethodSelection {
+		var out []*types.MethodSelection
+		for _, m := range in {
+			out = append(out, &types.MethodSelection{Name: m.Name, Method: byID(m.Method), ReceiverKind: m.ReceiverKind, Path: m.Path})
+		}
+		return out
+	}
+	params := func(in []cachedParamResult) []*types.ParamResult {
+		var out []*types.ParamResult
+		for _, p := range in {
+			out = append(out, &types.ParamResult{Name: p.Name, Type: byID(p.Type)})
+		}
+		return out
+	}
+
+	for i, ct := range cu.Types {
+		t := all[i]
+		if t.Kind != types.Unknown {
+			continue // A builtin.
+		}
+		*t = types.Type{
+			Name:                              ct.Name,
+			Kind:                              ct.Kind,
+			Position:                          ct.Position,
+			DeclarationOrder:                  ct.DeclarationOrder,
+			CommentLines:                      ct.CommentLines,
+			CommentLinePositions:              ct.CommentLinePositions,
+			TrailingCommentLines:              ct.TrailingCommentLines,
+			TrailingCommentLinePositions:      ct.TrailingCommentLinePositions,
+			SecondClosestCommentLines:         ct.SecondClosestCommentLines,
+			SecondClosestCommentLinePositions: ct.SecondClosestCommentLinePositions,
+			TypeParams:                        byIDMap(ct.TypeParams),
+			TypeParamList:                     byIDList(ct.TypeParamList),
+			Constraint:                        byID(ct.Constraint),
+			Origin:                            byID(ct.Origin),
+			TypeArgs:                          byIDList(ct.TypeArgs),
+			Elem:                              byID(ct.Elem),
+			Key:                               byID(ct.Key),
+			Underlying:                        byID(ct.Underlying),
+			Methods:                           byIDMap(ct.Methods),
+			MethodSet:                         methodSet(ct.MethodSet),
+			PointerMethodSet:                  methodSet(ct.PointerMethodSet),
+			EmbeddedInterfaces:                byIDList(ct.EmbeddedInterfaces),
+			ConstValue:                        ct.ConstValue,
+			ConstDecl:                         ct.ConstDecl,
+			ChanDir:                           ct.ChanDir,
+			Len:                               ct.Len,
+		}
+		for _, m := range ct.Members {
+			t.Members = append(t.Members, types.Member{
+				Name:                         m.Name,
+				Embedded:                     m.Embedded,
+				Position:                     m.Position,
+				CommentLines:                 m.CommentLines,
+				CommentLinePositions:         m.CommentLinePositions,
+				TrailingCommentLines:         m.TrailingCommentLines,
+				TrailingCommentLinePositions: m.TrailingCommentLinePositions,
+				Tags:                         m.Tags,
+				Type:                         byID(m.Type),
+			})
+		}
+		for _, union := range ct.Unions {
+			var terms []types.UnionTerm
+			for _, term := range union {
+				terms = append(terms, types.UnionTerm{Tilde: term.Tilde, Type: byID(term.Type)})
+			}
+			t.Unions = append(t.Unions, terms)
+		}
+		if sig := ct.Signature; sig != nil {
+			t.Signature = &types.Signature{
+				Receiver:     byID(sig.Receiver),
+				Parameters:   params(sig.Parameters),
+				Results:      params(sig.Results),
+				Variadic:     sig.Variadic,
+				TypeParams:   byIDList(sig.TypeParams),
+				CommentLines: sig.CommentLines,
+			}
+		}
+	}
+
+	for _, cp := range cu.Packages {
+		pkg := u.Package(cp.Path)
+		pkg.Dir = cp.Dir
+		pkg.GoFiles = cp.GoFiles
+		pkg.TestGoFiles = cp.TestGoFiles
+		pkg.Name = cp.Name
+		pkg.DocComments = cp.DocComments
+		pkg.Comments = cp.Comments
+		maps.Copy(pkg.Types, byIDMap(cp.Types))
+		maps.Copy(pkg.Functions, byIDMap(cp.Functions))
+		maps.Copy(pkg.Variables, byIDMap(cp.Variables))
+		maps.Copy(pkg.Constants, byIDMap(cp.Constants))
+		maps.Copy(pkg.Instances, byIDMap(cp.Instances))
+		u.AddImports(cp.Path, cp.Imports...)
+	}
+	return u
+}
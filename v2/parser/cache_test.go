@@ -0,0 +1,166 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/gengo/v2/types"
+)
+
+func TestParseCache(t *testing.T) {
+	dir := t.TempDir()
+	patterns := []string{"./testdata/basic", "./testdata/generic-decls", "./testdata/interfaces", "./testdata/methodsets", "./testdata/trailing-comments", "./testdata/enums"}
+	parse := func(opts Options) (*Parser, types.Universe) {
+		t.Helper()
+		opts.CacheDir = dir
+		p := NewWithOptions(opts)
+		if err := p.LoadPackages(patterns...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		u, err := p.NewUniverse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return p, u
+	}
+
+	first, parsed := parse(Options{})
+	if len(first.goPkgs) == 0 {
+		t.Errorf("expected the packages to be loaded")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.gob")); len(files) != 1 {
+		t.Errorf("expected one cache file, got %q", files)
+	}
+
+	second, cached := parse(Options{})
+	if len(second.goPkgs) != 0 {
+		t.Errorf("expected the packages to be found in the cache, but they were loaded")
+	}
+	if want, got := first.UserRequestedPackages(), second.UserRequestedPackages(); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong user-requested packages: want %q, got %q", want, got)
+	}
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(types.Type{}, "GoType"),
+		cmpopts.EquateEmpty(),
+	}
+	if diff := cmp.Diff(parsed, cached, opts...); diff != "" {
+		t.Errorf("cached universe differs (-parsed +cached):\n%s", diff)
+	}
+	blah := cached.Type(types.Name{Package: "k8s.io/gengo/v2/parser/testdata/basic", Name: "Blah"})
+	if blah.Members[1].Type != types.String {
+		t.Errorf("expected builtin types to be canonical, got %p for %p", blah.Members[1].Type, types.String)
+	}
+	if !blah.IsComparable() {
+		t.Errorf("expected Blah to be comparable without its GoType")
+	}
+
+	// A change to any file means the packages must be loaded again.
+	file, err := filepath.Abs("./testdata/basic/file.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	third, _ := parse(Options{Overlay: map[string][]byte{file: append(b, "\ntype New struct{}\n"...)}})
+	if len(third.goPkgs) == 0 {
+		t.Errorf("expected the packages to be loaded after a change")
+	}
+	// As do other options.
+	fourth, _ := parse(Options{BuildTags: []string{"foo"}})
+	if len(fourth.goPkgs) == 0 {
+		t.Errorf("expected the packages to be loaded with other build tags")
+	}
+	// And the environment and directory in which the go command is run.
+	fifth, _ := parse(Options{Env: append(os.Environ(), "GODEBUG=gotypesalias=1")})
+	if len(fifth.goPkgs) == 0 {
+		t.Errorf("expected the packages to be loaded in another environment")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sixth, _ := parse(Options{Dir: wd})
+	if len(sixth.goPkgs) == 0 {
+		t.Errorf("expected the packages to be loaded in another directory")
+	}
+}
+
+func TestParseCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * cacheMaxAge)
+	unused := filepath.Join(dir, "unused.gob")
+	recent := filepath.Join(dir, "recent.gob")
+	other := filepath.Join(dir, "other.txt")
+	for _, path := range []string{unused, recent, other} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{unused, other} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parse := func() {
+		t.Helper()
+		p := NewWithOptions(Options{CacheDir: dir})
+		if err := p.LoadPackages("./testdata/basic"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := p.NewUniverse(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	parse()
+	if _, err := os.Stat(unused); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the unused cache file to be removed, got %v", err)
+	}
+	for _, path := range []string{recent, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept: %v", filepath.Base(path), err)
+		}
+	}
+
+	// Reading a cached Universe marks it as used.
+	files, _ := filepath.Glob(filepath.Join(dir, "*.gob"))
+	cached := slices.DeleteFunc(files, func(path string) bool { return path == recent })
+	if len(cached) != 1 {
+		t.Fatalf("expected one new cache file, got %q", cached)
+	}
+	if err := os.Chtimes(cached[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+	parse()
+	if info, err := os.Stat(cached[0]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if !info.ModTime().After(old.Add(cacheMaxAge)) {
+		t.Errorf("expected the cache file to be marked as used, got %v", info.ModTime())
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package parser
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -32,6 +33,7 @@ import (
 	"time"
 
 	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
 
 	"k8s.io/gengo/v2/types"
 	"k8s.io/klog/v2"
@@ -54,6 +56,23 @@ type Parser struct {
 	// Build tags to set when loading packages.
 	buildTags []string
 
+	// Whether to load packages along with their _test.go files.
+	includeTestFiles bool
+
+	// Contents of files to use in place of those on disk.
+	overlay map[string][]byte
+
+	// The directory and environment in which to run the go command.
+	dir string
+	env []string
+
+	// Where to cache parsed Universes, if anywhere. If the packages which
+	// were loaded first were found in the cache, cached is that Universe.
+	// If not, cacheKey is what to save NewUniverse's result as.
+	cacheDir string
+	cached   *cachedUniverse
+	cacheKey string
+
 	// Tracks accumulated parsed files, so we can do position lookups later.
 	fset *token.FileSet
 
@@ -63,6 +82,28 @@ type Parser struct {
 	// function definition), which is what we almost always want.  We need this
 	// because Go's own ast package does a very poor job of handling comments.
 	endLineToCommentGroup map[fileLine]*ast.CommentGroup
+
+	// The comments at the ends of the lines of struct fields, interface
+	// methods, constants, and variables (which go/ast calls line comments),
+	// keyed by the position of the name which go/types gives the object.
+	lineComments map[token.Pos]*ast.CommentGroup
+
+	// Where each constant is declared within its const declaration, keyed
+	// by the position of its name.
+	constDecls map[token.Pos]*types.ConstDecl
+
+	// Type parameters, which are not kept in the Universe: a type
+	// parameter is named only by its own name, so e.g. the T in Foo[T] and
+	// the T in Bar[T] would be confused. The type parameters of a method's
+	// receiver map to those of the receiver's generic type.
+	typeParams map[*gotypes.TypeParam]*types.Type
+
+	// Types which are not kept in the Universe by name, keyed by identity:
+	// instances of generic types (which are not declared by their package,
+	// and are kept as Universe.Instance if they don't refer to type
+	// parameters), and anonymous types which refer to type parameters (see
+	// typeParams).
+	localTypes typeutil.Map
 }
 
 // key type for finding comments.
@@ -83,7 +124,15 @@ func NewWithOptions(opts Options) *Parser {
 		fullyProcessed:        map[string]bool{},
 		fset:                  token.NewFileSet(),
 		endLineToCommentGroup: map[fileLine]*ast.CommentGroup{},
+		lineComments:          map[token.Pos]*ast.CommentGroup{},
+		constDecls:            map[token.Pos]*types.ConstDecl{},
 		buildTags:             opts.BuildTags,
+		includeTestFiles:      opts.IncludeTestFiles,
+		overlay:               opts.Overlay,
+		dir:                   opts.Dir,
+		env:                   opts.Env,
+		cacheDir:              opts.CacheDir,
+		typeParams:            map[*gotypes.TypeParam]*types.Type{},
 	}
 }
 
@@ -92,6 +141,41 @@ type Options struct {
 	// BuildTags is a list of optional tags to be specified when loading
 	// packages.
 	BuildTags []string
+
+	// IncludeTestFiles, if true, causes the requested packages to be loaded
+	// along with their _test.go files. What is declared in a package's own
+	// test files is part of that package (see types.Package.TestGoFiles),
+	// while an external test package (e.g. "package foo_test") is a package
+	// of its own, whose path is that of the package under test with the
+	// suffix "_test".
+	IncludeTestFiles bool
+
+	// Overlay maps absolute file paths to contents which are parsed in
+	// place of those files' contents on disk. A path which does not exist
+	// on disk adds a file to the package in its directory. See
+	// packages.Config.Overlay.
+	Overlay map[string][]byte
+
+	// Dir is the directory in which to run the go command, which finds and
+	// loads packages. This determines the module or workspace (go.work)
+	// which packages are loaded in, and the directory which relative
+	// patterns are relative to. If empty, the current directory is used.
+	Dir string
+
+	// Env is the environment in which to run the go command, e.g. to set
+	// GOFLAGS or GOWORK. If nil, the current environment is used. As for
+	// os/exec, if a variable is set more than once, the last one wins.
+	Env []string
+
+	// CacheDir, if not empty, is a directory in which to cache the parsed
+	// Universe, so that parsing the same packages again (e.g. by the next
+	// of several tools which are run on the same packages) does not need to
+	// type-check them. This is keyed by the contents of every file of the
+	// packages and their dependencies, and by the build tags and other
+	// options. Only the packages which are loaded first, by LoadPackages,
+	// are cached. The types of a cached Universe have no GoType. Cached
+	// Universes which have not been used for a week are removed.
+	CacheDir string
 }
 
 // FindPackages expands the provided patterns into a list of Go import-paths,
@@ -119,6 +203,9 @@ func (p *Parser) findPackages(baseCfg *packages.Config, patterns ...string) ([]s
 		Mode:       packages.NeedName | packages.NeedFiles,
 		BuildFlags: []string{"-tags", strings.Join(p.buildTags, ",")},
 		Tests:      false,
+		Overlay:    p.overlay,
+		Dir:        p.dir,
+		Env:        p.env,
 	}
 	if baseCfg != nil {
 		// This is to support tests, e.g. to inject a fake GOPATH or CWD.
@@ -154,10 +241,45 @@ func (p *Parser) findPackages(baseCfg *packages.Config, patterns ...string) ([]s
 // named packages (without a trailing "/...") which do not exist or have no Go
 // files are an error.
 func (p *Parser) LoadPackages(patterns ...string) error {
+	if p.cacheDir != "" {
+		first := len(p.userRequested) == 0
+		p.cacheKey = ""
+		if first {
+			if found, err := p.loadCachedUniverse(patterns); err != nil {
+				return err
+			} else if found {
+				return nil
+			}
+		}
+	}
 	_, err := p.loadPackages(patterns...)
 	return err
 }
 
+// loadCachedUniverse looks for the Universe which loading patterns would
+// produce in the cache. If it is not found, NewUniverse will save it.
+func (p *Parser) loadCachedUniverse(patterns []string) (bool, error) {
+	key, err := p.cacheKeyFor(patterns)
+	if err != nil {
+		return false, err
+	}
+	cu, err := p.readCache(key)
+	if err != nil {
+		return false, err
+	}
+	if cu == nil {
+		klog.V(5).Infof("parse cache miss for %q", patterns)
+		p.cacheKey = key
+		return false, nil
+	}
+	klog.V(5).Infof("parse cache hit for %q", patterns)
+	p.cached = cu
+	for _, pkgPath := range cu.UserRequested {
+		p.userRequested[pkgPath] = true
+	}
+	return true, nil
+}
+
 // LoadPackagesWithConfigForTesting loads and parses the specified Go packages with the
 // specified packages.Config as a starting point.  This is for testing, and
 // only the .Dir and .Env fields of the Config will be considered.
@@ -171,6 +293,9 @@ func (p *Parser) LoadPackagesWithConfigForTesting(cfg *packages.Config, patterns
 // patterns, but loads all packages and their imports, recursively, into the
 // universe.  See NewUniverse for more.
 func (p *Parser) LoadPackagesTo(u *types.Universe, patterns ...string) ([]*types.Package, error) {
+	// The Universe which was loaded first can no longer be cached as is.
+	p.cacheKey = ""
+
 	// Load Packages.
 	pkgs, err := p.loadPackages(patterns...)
 	if err != nil {
@@ -240,7 +365,10 @@ func (p *Parser) loadPackagesWithConfig(baseCfg *packages.Config, patterns ...st
 			packages.NeedModule | packages.NeedTypes | packages.NeedSyntax,
 		BuildFlags: []string{"-tags", strings.Join(p.buildTags, ",")},
 		Fset:       p.fset,
-		Tests:      false,
+		Tests:      p.includeTestFiles,
+		Overlay:    p.overlay,
+		Dir:        p.dir,
+		Env:        p.env,
 	}
 	if baseCfg != nil {
 		// This is to support tests, e.g. to inject a fake GOPATH or CWD.
@@ -254,6 +382,14 @@ func (p *Parser) loadPackagesWithConfig(baseCfg *packages.Config, patterns ...st
 		return nil, fmt.Errorf("error loading packages: %w", err)
 	}
 	klog.V(5).Infof("  loaded %d pkg(s) in %v", len(pkgs), time.Since(tBefore))
+	if p.includeTestFiles {
+		pkgs = testVariants(pkgs)
+		// External test packages are not found by their own paths, but they
+		// were requested all the same.
+		for _, pkg := range pkgs {
+			p.userRequested[pkg.PkgPath] = true
+		}
+	}
 
 	// Handle any errors.
 	collectErrors := func(pkg *packages.Package) error {
@@ -292,6 +428,8 @@ func (p *Parser) loadPackagesWithConfig(baseCfg *packages.Config, patterns ...st
 				position := p.fset.Position(c.End()) // Fset is synchronized
 				p.endLineToCommentGroup[fileLine{position.Filename, position.Line}] = c
 			}
+			p.addLineComments(f)
+			p.addConstDecls(f)
 		}
 
 		return nil
@@ -303,6 +441,34 @@ func (p *Parser) loadPackagesWithConfig(baseCfg *packages.Config, patterns ...st
 	return append(existingPkgs, pkgs...), nil
 }
 
+// testVariants returns the packages which were loaded with their tests, with
+// each package replaced by its test variant (the package along with its own
+// _test.go files), if it has one, and without the generated test mains.
+// External test packages are kept.
+func testVariants(pkgs []*packages.Package) []*packages.Package {
+	variants := map[string]*packages.Package{}
+	for _, pkg := range pkgs {
+		// Test variants are identified as e.g. "foo [foo.test]".
+		if pkg.ID != pkg.PkgPath && !strings.HasSuffix(pkg.PkgPath, "_test") {
+			variants[pkg.PkgPath] = pkg
+		}
+	}
+	var out []*packages.Package
+	for _, pkg := range pkgs {
+		switch {
+		case pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test"):
+			// A generated test main.
+		case pkg.ID == pkg.PkgPath && variants[pkg.PkgPath] != nil:
+			out = append(out, variants[pkg.PkgPath])
+		case pkg.ID != pkg.PkgPath && variants[pkg.PkgPath] == pkg:
+			// Already added in place of the package.
+		default:
+			out = append(out, pkg)
+		}
+	}
+	return out
+}
+
 // alreadyLoaded figures out which of the specified patterns have already been loaded
 // and which have not, and returns those respectively.
 // baseCfg is an optional (may be nil) config which might be injected by tests.
@@ -373,15 +539,27 @@ func (p *Parser) UserRequestedPackages() []string {
 // represents "builtin" types.
 func (p *Parser) NewUniverse() (types.Universe, error) {
 	u := types.Universe{}
+	if p.cached != nil {
+		u = decodeUniverse(p.cached)
+	}
 
 	pkgs := []*packages.Package{}
 	for _, path := range p.UserRequestedPackages() {
-		pkgs = append(pkgs, p.goPkgs[path])
+		// Those which were found in the cache were not loaded.
+		if pkg := p.goPkgs[path]; pkg != nil {
+			pkgs = append(pkgs, pkg)
+		}
 	}
 	if err := p.addPkgsToUniverse(pkgs, &u); err != nil {
 		return nil, err
 	}
 
+	if p.cacheKey != "" {
+		if err := p.writeCache(p.cacheKey, u); err != nil {
+			klog.Warningf("Failed to save the parse cache: %v", err)
+		}
+		p.cacheKey = ""
+	}
 	return u, nil
 }
 
@@ -414,7 +592,8 @@ func minimize(lines []string) []string {
 // addCommentsToType takes any accumulated comment lines prior to obj and
 // attaches them to the type t.
 func (p *Parser) addCommentsToType(obj gotypes.Object, t *types.Type) {
-	if newLines, oldLines := p.docComment(obj.Pos()), t.CommentLines; len(newLines) > 0 {
+	newLines, newPositions := p.docComment(obj.Pos())
+	if oldLines := t.CommentLines; len(newLines) > 0 {
 		switch {
 		case reflect.DeepEqual(oldLines, newLines):
 			// nothing needed
@@ -422,6 +601,7 @@ func (p *Parser) addCommentsToType(obj gotypes.Object, t *types.Type) {
 		case len(oldLines) == 0:
 			// no comments associated, or comments match exactly
 			t.CommentLines = newLines
+			t.CommentLinePositions = newPositions
 
 		case isTypeAlias(obj.Type()):
 			// Ignore mismatched comments from obj because it's an alias.
@@ -442,6 +622,7 @@ func (p *Parser) addCommentsToType(obj gotypes.Object, t *types.Type) {
 			// overwriting the "real" comments with an alias's comments, but
 			// it is not clear if we can assume which one is the "real" one.
 			t.CommentLines = newLines
+			t.CommentLinePositions = newPositions
 			if !reflect.DeepEqual(minimize(oldLines), minimize(newLines)) {
 				klog.Warningf(
 					"Mismatched comments on type %v.\n  Using comments:\n%s\n  Ignoring comments from possible type alias:\n%s\n",
@@ -453,7 +634,8 @@ func (p *Parser) addCommentsToType(obj gotypes.Object, t *types.Type) {
 		}
 	}
 
-	if newLines, oldLines := p.priorDetachedComment(obj.Pos()), t.SecondClosestCommentLines; len(newLines) > 0 {
+	newLines, newPositions = p.priorDetachedComment(obj.Pos())
+	if oldLines := t.SecondClosestCommentLines; len(newLines) > 0 {
 		switch {
 		case reflect.DeepEqual(oldLines, newLines):
 			// nothing needed
@@ -461,6 +643,7 @@ func (p *Parser) addCommentsToType(obj gotypes.Object, t *types.Type) {
 		case len(oldLines) == 0:
 			// no comments associated, or comments match exactly
 			t.SecondClosestCommentLines = newLines
+			t.SecondClosestCommentLinePositions = newPositions
 
 		case isTypeAlias(obj.Type()):
 			// Ignore mismatched comments from obj because it's an alias.
@@ -482,6 +665,7 @@ func (p *Parser) addCommentsToType(obj gotypes.Object, t *types.Type) {
 			// overwriting the "real" comments with an alias's comments, but
 			// it is not clear if we can assume which one is the "real" one.
 			t.SecondClosestCommentLines = newLines
+			t.SecondClosestCommentLinePositions = newPositions
 			if !reflect.DeepEqual(minimize(oldLines), minimize(newLines)) {
 				klog.Warningf(
 					"Mismatched secondClosestCommentLines on type %v.\n  Using comments:\n%s\n  Ignoring comments from possible type alias:\n%s\n",
@@ -502,10 +686,18 @@ func formatCommentBlock(lines []string) string {
 func packageDir(pkg *packages.Package) (string, error) {
 	// Sometimes Module is present but has no Dir, e.g. when it is vendored.
 	if pkg.Module != nil && pkg.Module.Dir != "" {
-		// NOTE: this will not work if tests are loaded, because Go mutates the
-		// Package.PkgPath.
-		subdir := strings.TrimPrefix(pkg.PkgPath, pkg.Module.Path)
-		return filepath.Join(pkg.Module.Dir, subdir), nil
+		pkgPath := pkg.PkgPath
+		if pkg.ID != pkg.PkgPath {
+			// This is a test variant, and the path of an external test
+			// package is that of the package under test plus "_test".
+			pkgPath = strings.TrimSuffix(pkgPath, "_test")
+		}
+		if pkgPath == pkg.Module.Path {
+			return pkg.Module.Dir, nil
+		}
+		if subdir, ok := strings.CutPrefix(pkgPath, pkg.Module.Path+"/"); ok {
+			return filepath.Join(pkg.Module.Dir, filepath.FromSlash(subdir)), nil
+		}
 	}
 	if len(pkg.GoFiles) > 0 {
 		return filepath.Dir(pkg.GoFiles[0]), nil
@@ -561,6 +753,13 @@ func (p *Parser) addPkgToUniverse(pkg *packages.Package, u *types.Universe) erro
 
 		gengoPkg.Path = pkg.PkgPath
 		gengoPkg.Dir = absPath
+		for _, file := range pkg.GoFiles {
+			if strings.HasSuffix(file, "_test.go") {
+				gengoPkg.TestGoFiles = append(gengoPkg.TestGoFiles, file)
+			} else {
+				gengoPkg.GoFiles = append(gengoPkg.GoFiles, file)
+			}
+		}
 	}
 
 	// If the package was not user-requested, we can stop here.
@@ -593,30 +792,47 @@ func (p *Parser) addPkgToUniverse(pkg *packages.Package, u *types.Universe) erro
 
 	// Walk all the types, recursively and save them for later access.
 	s := pkg.Types.Scope()
+	named := map[*gotypes.TypeName]*types.Type{}
+	var decls []declaration
 	for _, n := range s.Names() {
 		switch obj := s.Lookup(n).(type) {
 		case *gotypes.TypeName:
 			t := p.walkType(*u, nil, obj.Type())
 			p.addCommentsToType(obj, t)
+			decls = append(decls, declaration{obj, t})
+			if !obj.IsAlias() {
+				named[obj] = t
+			}
 		case *gotypes.Func:
 			// We only care about functions, not concrete/abstract methods.
 			if obj.Type() != nil && obj.Type().(*gotypes.Signature).Recv() == nil {
 				t := p.addFunction(*u, nil, obj)
 				p.addCommentsToType(obj, t)
+				decls = append(decls, declaration{obj, t})
 			}
 		case *gotypes.Var:
 			if !obj.IsField() {
 				t := p.addVariable(*u, nil, obj)
 				p.addCommentsToType(obj, t)
+				decls = append(decls, declaration{obj, t})
 			}
 		case *gotypes.Const:
 			t := p.addConstant(*u, nil, obj)
 			p.addCommentsToType(obj, t)
+			decls = append(decls, declaration{obj, t})
 		default:
 			klog.Infof("addPkgToUniverse %q: unhandled object of type %T: %v", pkgPath, obj, obj)
 		}
 	}
 
+	// Now that all of the methods which they might refer to have been
+	// added, add the method sets.
+	for obj, t := range named {
+		t.MethodSet = p.walkMethodSet(*u, obj.Type())
+		t.PointerMethodSet = p.walkMethodSet(*u, gotypes.NewPointer(obj.Type()))
+	}
+	p.addDeclarationOrder(pkg, decls)
+
 	// Add all of this package's imports.
 	importedPkgs := []string{}
 	// Iterate imports in a predictable order
@@ -633,17 +849,146 @@ func (p *Parser) addPkgToUniverse(pkg *packages.Package, u *types.Universe) erro
 	return nil
 }
 
-// If the specified position has a "doc comment", return that.
-func (p *Parser) docComment(pos token.Pos) []string {
+// addLineComments records the line comments of the fields, interface
+// methods, constants, and variables in f.
+func (p *Parser) addLineComments(f *ast.File) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			if n.Comment == nil {
+				break
+			}
+			for _, name := range n.Names {
+				p.lineComments[name.Pos()] = n.Comment
+			}
+			if len(n.Names) == 0 {
+				if id := embeddedFieldIdent(n.Type); id != nil {
+					p.lineComments[id.Pos()] = n.Comment
+				}
+			}
+		case *ast.ValueSpec:
+			if n.Comment == nil {
+				break
+			}
+			for _, name := range n.Names {
+				p.lineComments[name.Pos()] = n.Comment
+			}
+		}
+		return true
+	})
+}
+
+// addConstDecls records where each of the constants declared in f is
+// declared within its const declaration.
+func (p *Parser) addConstDecls(f *ast.File) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		block := p.fset.Position(gd.Pos())
+		// A spec with neither a type nor values repeats the previous
+		// values.
+		usesIota := false
+		for i, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if vs.Type != nil || len(vs.Values) != 0 {
+				usesIota = false
+				for _, v := range vs.Values {
+					usesIota = usesIota || mentionsIota(v)
+				}
+			}
+			for _, name := range vs.Names {
+				p.constDecls[name.Pos()] = &types.ConstDecl{Block: block, Iota: i, UsesIota: usesIota}
+			}
+		}
+	}
+}
+
+// mentionsIota returns whether expr refers to iota.
+func mentionsIota(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// embeddedFieldIdent returns the identifier which names an embedded field
+// of type e, whose position is that of the field. This is the same logic as
+// in go/types.
+func embeddedFieldIdent(e ast.Expr) *ast.Ident {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		if _, ok := e.X.(*ast.StarExpr); !ok {
+			return embeddedFieldIdent(e.X)
+		}
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedFieldIdent(e.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldIdent(e.X)
+	}
+	return nil
+}
+
+// declaration is an object which a package declares, and its Type.
+type declaration struct {
+	obj gotypes.Object
+	t   *types.Type
+}
+
+// addDeclarationOrder numbers the declarations of pkg in the order in which
+// they appear in its files.
+func (p *Parser) addDeclarationOrder(pkg *packages.Package, decls []declaration) {
+	files := map[string]int{}
+	for i, f := range pkg.Syntax {
+		files[p.fset.Position(f.FileStart).Filename] = i
+	}
+	slices.SortFunc(decls, func(a, b declaration) int {
+		pa, pb := p.fset.Position(a.obj.Pos()), p.fset.Position(b.obj.Pos())
+		return cmp.Or(
+			cmp.Compare(files[pa.Filename], files[pb.Filename]),
+			cmp.Compare(pa.Offset, pb.Offset),
+		)
+	})
+	n := 0
+	for _, d := range decls {
+		// An alias declares a name for another type, not the type
+		// itself.
+		if d.t.Position != p.fset.Position(d.obj.Pos()) {
+			continue
+		}
+		n++
+		d.t.DeclarationOrder = n
+	}
+}
+
+// If the specified position has a "line comment", i.e. a comment after the
+// declaration on the same line, return that, along with the position of each
+// line.
+func (p *Parser) lineComment(pos token.Pos) ([]string, []token.Position) {
+	return p.commentLines(p.lineComments[pos])
+}
+
+// If the specified position has a "doc comment", return that, along with
+// the position of each line.
+func (p *Parser) docComment(pos token.Pos) ([]string, []token.Position) {
 	// An object's doc comment always ends on the line before the object's own
 	// declaration.
 	c1 := p.priorCommentLines(pos, 1)
-	return splitLines(c1.Text()) // safe even if c1 is nil
+	return p.commentLines(c1)
 }
 
 // If there is a detached (not immediately before a declaration) comment,
-// return that.
-func (p *Parser) priorDetachedComment(pos token.Pos) []string {
+// return that, along with the position of each line.
+func (p *Parser) priorDetachedComment(pos token.Pos) ([]string, []token.Position) {
 	// An object's doc comment always ends on the line before the object's own
 	// declaration.
 	c1 := p.priorCommentLines(pos, 1)
@@ -657,7 +1002,97 @@ func (p *Parser) priorDetachedComment(pos token.Pos) []string {
 	} else {
 		c2 = p.priorCommentLines(c1.List[0].Slash, 2)
 	}
-	return splitLines(c2.Text()) // safe even if c1 is nil
+	return p.commentLines(c2)
+}
+
+// commentLines returns the lines of text in cg (as per cg.Text()), and the
+// position of each line.
+func (p *Parser) commentLines(cg *ast.CommentGroup) ([]string, []token.Position) {
+	lines := splitLines(cg.Text()) // safe even if cg is nil
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	// Text() strips comment markers and directives, and drops some blank
+	// lines, so the lines it returns are a subsequence of the raw comment
+	// lines. Walk both to find where each one came from.
+	type rawLine struct {
+		text      string
+		directive bool
+		pos       token.Position
+	}
+	var raw []rawLine
+	for _, c := range cg.List {
+		pos := p.fset.Position(c.Slash)
+		if c.Text[1] == '/' {
+			text := c.Text[2:]
+			directive := false
+			if strings.HasPrefix(text, " ") {
+				text = text[1:]
+			} else if text != "" {
+				directive = isDirective(text)
+			}
+			raw = append(raw, rawLine{strings.TrimRight(text, " \t\r\n"), directive, pos})
+			continue
+		}
+		// A /*-style comment may span lines.
+		text := c.Text[2 : len(c.Text)-2]
+		offset := 2
+		for i, line := range strings.Split(text, "\n") {
+			linePos := pos
+			if i > 0 {
+				linePos.Line += i
+				linePos.Column = 1
+				linePos.Offset += offset
+			}
+			raw = append(raw, rawLine{strings.TrimRight(line, " \t\r\n"), false, linePos})
+			offset += len(line) + 1
+		}
+	}
+
+	positions := make([]token.Position, len(lines))
+	j := 0
+	for i, line := range lines {
+		for j < len(raw) && (raw[j].directive || raw[j].text != line) {
+			j++
+		}
+		if j == len(raw) {
+			// Should not happen, but don't make up positions if it does.
+			klog.Warningf("Unable to find positions for comment at %v", p.fset.Position(cg.Pos()))
+			return lines, nil
+		}
+		positions[i] = raw[j].pos
+		j++
+	}
+	return lines, positions
+}
+
+// isDirective reports whether c (with the leading "//" removed) is a comment
+// directive, which ast.CommentGroup.Text omits. This is the same logic as in
+// go/ast.
+func isDirective(c string) bool {
+	// "//line " is a line directive.
+	// "//extern " is for gccgo.
+	// "//export " is for cgo.
+	if strings.HasPrefix(c, "line ") || strings.HasPrefix(c, "extern ") || strings.HasPrefix(c, "export ") {
+		return true
+	}
+
+	// "//[a-z0-9]+:[a-z0-9]"
+	colon := strings.Index(c, ":")
+	if colon <= 0 || colon+1 >= len(c) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		b := c[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
 }
 
 // If there's a comment block which ends nlines before pos, return it.
@@ -731,6 +1166,20 @@ func goNameToName(in string) types.Name {
 
 func (p *Parser) convertSignature(u types.Universe, t *gotypes.Signature) *types.Signature {
 	signature := &types.Signature{}
+	if tps := t.RecvTypeParams(); tps.Len() != 0 {
+		// A method of a generic type declares its own type parameters in
+		// its receiver. Treat them as the generic type's, so that e.g. the
+		// receiver is the generic type itself.
+		origin := receiverBase(t.Recv().Type()).Origin()
+		for i := 0; i < tps.Len(); i++ {
+			tp := p.walkTypeParam(u, origin.TypeParams().At(i))
+			p.typeParams[tps.At(i)] = tp
+			signature.TypeParams = append(signature.TypeParams, tp)
+		}
+	}
+	for i := 0; i < t.TypeParams().Len(); i++ {
+		signature.TypeParams = append(signature.TypeParams, p.walkTypeParam(u, t.TypeParams().At(i)))
+	}
 	for i := 0; i < t.Params().Len(); i++ {
 		signature.Parameters = append(signature.Parameters, &types.ParamResult{
 			Name: t.Params().At(i).Name(),
@@ -750,6 +1199,333 @@ func (p *Parser) convertSignature(u types.Universe, t *gotypes.Signature) *types
 	return signature
 }
 
+// receiverBase returns the named type of a method receiver, which may be a
+// pointer.
+func receiverBase(recv gotypes.Type) *gotypes.Named {
+	if ptr, ok := recv.(*gotypes.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	return recv.(*gotypes.Named)
+}
+
+// lookupType gets the Type for in, which has the specified name, creating
+// it if needed. The caller is expected to finish initialization of a new
+// Type. If local is true, the Type is not kept in the Universe.
+func (p *Parser) lookupType(u types.Universe, name types.Name, in gotypes.Type, local bool) *types.Type {
+	if !local {
+		return u.Type(name)
+	}
+	if out, ok := p.localTypes.At(in).(*types.Type); ok {
+		return out
+	}
+	out := &types.Type{Name: name}
+	p.localTypes.Set(in, out)
+	return out
+}
+
+// mentionsTypeParams returns true if t refers to any type parameters.
+func mentionsTypeParams(t gotypes.Type) bool {
+	tupleMentions := func(tuple *gotypes.Tuple) bool {
+		for i := 0; i < tuple.Len(); i++ {
+			if mentionsTypeParams(tuple.At(i).Type()) {
+				return true
+			}
+		}
+		return false
+	}
+	switch t := t.(type) {
+	case *gotypes.TypeParam:
+		return true
+	case *gotypes.Pointer:
+		return mentionsTypeParams(t.Elem())
+	case *gotypes.Slice:
+		return mentionsTypeParams(t.Elem())
+	case *gotypes.Array:
+		return mentionsTypeParams(t.Elem())
+	case *gotypes.Chan:
+		return mentionsTypeParams(t.Elem())
+	case *gotypes.Map:
+		return mentionsTypeParams(t.Key()) || mentionsTypeParams(t.Elem())
+	case *gotypes.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if mentionsTypeParams(t.Field(i).Type()) {
+				return true
+			}
+		}
+	case *gotypes.Signature:
+		return t.TypeParams().Len() != 0 || tupleMentions(t.Params()) || tupleMentions(t.Results())
+	case *gotypes.Interface:
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			if mentionsTypeParams(t.EmbeddedType(i)) {
+				return true
+			}
+		}
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			if mentionsTypeParams(t.ExplicitMethod(i).Type()) {
+				return true
+			}
+		}
+	case *gotypes.Union:
+		for i := 0; i < t.Len(); i++ {
+			if mentionsTypeParams(t.Term(i).Type()) {
+				return true
+			}
+		}
+	case *gotypes.Named:
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if mentionsTypeParams(t.TypeArgs().At(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// walkTypeParam adds a type parameter and its constraint.
+func (p *Parser) walkTypeParam(u types.Universe, in *gotypes.TypeParam) *types.Type {
+	if out, ok := p.typeParams[in]; ok {
+		return out
+	}
+	out := &types.Type{
+		Name:     types.Name{Name: in.Obj().Name()},
+		Kind:     types.TypeParam,
+		Position: p.fset.Position(in.Obj().Pos()),
+		GoType:   in,
+	}
+	// Add it before walking the constraint, which may refer to it.
+	p.typeParams[in] = out
+	out.Constraint = p.walkType(u, nil, in.Constraint())
+	return out
+}
+
+// walkTypeParams adds a list of type parameters, and returns them both by
+// name (mapped to their constraints) and in order.
+func (p *Parser) walkTypeParams(u types.Universe, in *gotypes.TypeParamList) (map[string]*types.Type, []*types.Type) {
+	byName := map[string]*types.Type{}
+	var list []*types.Type
+	for i := 0; i < in.Len(); i++ {
+		tp := p.walkTypeParam(u, in.At(i))
+		byName[tp.Name.Name] = tp.Constraint
+		list = append(list, tp)
+	}
+	return byName, list
+}
+
+// genericName returns the name of t, which may be a generic type. A generic
+// type's name includes the names of its type parameters, but not their
+// constraints, e.g. Foo[T,U].
+func genericName(t *gotypes.Named) types.Name {
+	name := goNameToName(t.String())
+	if tps := t.TypeParams(); tps.Len() != 0 {
+		name.Name = strings.SplitN(name.Name, "[", 2)[0] + typeParamNames(tps)
+	}
+	return name
+}
+
+// typeParamNames formats the names of a list of type parameters as they
+// appear in the name of a generic type or function, e.g. "[T,U]".
+func typeParamNames(tps *gotypes.TypeParamList) string {
+	var names []string
+	for i := 0; i < tps.Len(); i++ {
+		names = append(names, tps.At(i).Obj().Name())
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// methodName returns the name of a method, which is the method's signature,
+// including its receiver. A generic receiver is named as by genericName.
+func methodName(method *gotypes.Func) types.Name {
+	s := method.String()
+	if recv := method.Type().(*gotypes.Signature).Recv(); recv != nil {
+		// The methods of a generic interface have the interface itself as
+		// their receiver, which would include the type parameters'
+		// constraints.
+		if named, ok := recv.Type().(*gotypes.Named); ok && named.TypeParams().Len() != 0 && named.TypeArgs().Len() == 0 {
+			s = strings.Replace(s, named.String(), genericName(named).String(), 1)
+		}
+	}
+	return goNameToName(s)
+}
+
+// isSelfReference returns true if t is an instance of a generic type whose
+// type arguments are that type's own type parameters, e.g. Foo[T] within
+// the declaration of Foo[T], or as the receiver of one of its methods.
+func (p *Parser) isSelfReference(u types.Universe, t *gotypes.Named) bool {
+	tps := t.Origin().TypeParams()
+	for i := 0; i < t.TypeArgs().Len(); i++ {
+		arg, ok := t.TypeArgs().At(i).(*gotypes.TypeParam)
+		if !ok || p.walkTypeParam(u, arg) != p.walkTypeParam(u, tps.At(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// walkInstance adds an instance of a generic type, e.g. Foo[int]. Instances
+// are not declared by the generic type's package, so they are not among its
+// Types.
+func (p *Parser) walkInstance(u types.Universe, t *gotypes.Named) *types.Type {
+	if out, ok := p.localTypes.At(t).(*types.Type); ok {
+		return out
+	}
+	// Walk the generic type and the type arguments first. If they refer to
+	// this instance, it will have been added by the time they're done.
+	origin := p.walkType(u, nil, t.Origin())
+	var args []*types.Type
+	for i := 0; i < t.TypeArgs().Len(); i++ {
+		args = append(args, p.walkType(u, nil, t.TypeArgs().At(i)))
+	}
+	if out, ok := p.localTypes.At(t).(*types.Type); ok {
+		return out
+	}
+
+	var out *types.Type
+	if mentionsTypeParams(t) {
+		out = &types.Type{Name: goNameToName(t.String()), Origin: origin, TypeArgs: args}
+	} else {
+		// Share concrete instances with Universe.Instantiate.
+		out = u.Instance(origin, args...)
+	}
+	// Add it before walking anything else, which may refer to it.
+	p.localTypes.Set(t, out)
+	if out.Kind != types.Unknown {
+		return out
+	}
+	out.Position = p.fset.Position(t.Obj().Pos())
+	out.GoType = t
+
+	switch underlying := t.Underlying().(type) {
+	case *gotypes.Struct:
+		out.Kind = types.Struct
+		out.Members = p.walkMembers(u, underlying)
+	case *gotypes.Interface:
+		out.Kind = types.Interface
+		out.EmbeddedInterfaces, out.Unions = p.walkInterfaceElements(u, underlying)
+		// These can't be kept in the Universe, where they'd be confused
+		// with the generic type's methods if the type arguments are type
+		// parameters.
+		underlying.Complete()
+		for i := 0; i < underlying.NumMethods(); i++ {
+			if out.Methods == nil {
+				out.Methods = map[string]*types.Type{}
+			}
+			method := underlying.Method(i)
+			sig := method.Type().(*gotypes.Signature)
+			mt := &types.Type{
+				Name:      methodName(method),
+				Kind:      types.Func,
+				Position:  p.fset.Position(method.Pos()),
+				Signature: p.convertSignature(u, sig),
+				GoType:    sig,
+			}
+			mt.CommentLines, mt.CommentLinePositions = p.docComment(method.Pos())
+			mt.TrailingCommentLines, mt.TrailingCommentLinePositions = p.lineComment(method.Pos())
+			out.Methods[method.Name()] = mt
+		}
+	case *gotypes.Named, *gotypes.Basic, *gotypes.Map, *gotypes.Slice:
+		out.Kind = types.Alias
+		out.Underlying = p.walkType(u, nil, underlying)
+	default:
+		// As for other named types, flatten the underlying type into this
+		// one.
+		flat := p.walkType(u, nil, underlying)
+		out.Kind = flat.Kind
+		out.Elem = flat.Elem
+		out.Key = flat.Key
+		out.Len = flat.Len
+		out.ChanDir = flat.ChanDir
+		out.Signature = flat.Signature
+	}
+	return out
+}
+
+// walkMethodSet returns the method set of t, sorted by name.
+func (p *Parser) walkMethodSet(u types.Universe, t gotypes.Type) []*types.MethodSelection {
+	var out []*types.MethodSelection
+	ms := gotypes.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		sel := ms.At(i)
+		// For instances, refer to the methods of the generic type.
+		method := sel.Obj().(*gotypes.Func).Origin()
+		m := &types.MethodSelection{
+			Name:         method.Name(),
+			ReceiverKind: types.ValueReceiver,
+		}
+		recv := method.Type().(*gotypes.Signature).Recv().Type()
+		if ptr, ok := recv.(*gotypes.Pointer); ok {
+			m.ReceiverKind = types.PointerReceiver
+			recv = ptr.Elem()
+		} else if gotypes.IsInterface(recv) {
+			m.ReceiverKind = types.InterfaceReceiver
+		}
+		m.Method = p.walkType(u, nil, recv).Methods[method.Name()]
+
+		// The last index is that of the method, the others are those of
+		// the embedded fields it is promoted through.
+		index := sel.Index()
+		cur := t
+		for _, i := range index[:len(index)-1] {
+			if ptr, ok := cur.(*gotypes.Pointer); ok {
+				cur = ptr.Elem()
+			}
+			f := cur.Underlying().(*gotypes.Struct).Field(i)
+			m.Path = append(m.Path, f.Name())
+			cur = f.Type()
+		}
+		out = append(out, m)
+	}
+	// The method set is sorted by the methods' ids, which qualify the
+	// names of unexported methods with their packages.
+	slices.SortStableFunc(out, func(a, b *types.MethodSelection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return out
+}
+
+// walkInterfaceElements adds the embedded interfaces and the unions of an
+// interface.
+func (p *Parser) walkInterfaceElements(u types.Universe, t *gotypes.Interface) ([]*types.Type, [][]types.UnionTerm) {
+	var embeddeds []*types.Type
+	var unions [][]types.UnionTerm
+	for i := 0; i < t.NumEmbeddeds(); i++ {
+		switch e := t.EmbeddedType(i).(type) {
+		case *gotypes.Union:
+			var terms []types.UnionTerm
+			for j := 0; j < e.Len(); j++ {
+				term := e.Term(j)
+				terms = append(terms, types.UnionTerm{Tilde: term.Tilde(), Type: p.walkType(u, nil, term.Type())})
+			}
+			unions = append(unions, terms)
+		default:
+			if gotypes.IsInterface(e) {
+				embeddeds = append(embeddeds, p.walkType(u, nil, e))
+			} else {
+				unions = append(unions, []types.UnionTerm{{Type: p.walkType(u, nil, e)}})
+			}
+		}
+	}
+	return embeddeds, unions
+}
+
+// walkMembers adds the fields of a struct.
+func (p *Parser) walkMembers(u types.Universe, t *gotypes.Struct) []types.Member {
+	var members []types.Member
+	for i := 0; i < t.NumFields(); i++ {
+		f := t.Field(i)
+		m := types.Member{
+			Name:     f.Name(),
+			Embedded: f.Anonymous(),
+			Tags:     t.Tag(i),
+			Type:     p.walkType(u, nil, f.Type()),
+			Position: p.fset.Position(f.Pos()),
+		}
+		m.CommentLines, m.CommentLinePositions = p.docComment(f.Pos())
+		m.TrailingCommentLines, m.TrailingCommentLinePositions = p.lineComment(f.Pos())
+		members = append(members, m)
+	}
+	return members
+}
+
 // walkType adds the type, and any necessary child types.
 func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type) *types.Type {
 	// Most of the cases are underlying types of the named type.
@@ -757,6 +1533,9 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 	if useName != nil {
 		name = *useName
 	}
+	// Anonymous types which refer to type parameters can't be kept in the
+	// Universe, for the same reason as type parameters themselves.
+	local := useName == nil && name.Package == "" && mentionsTypeParams(in)
 
 	// Handle alias types conditionally on go1.22+.
 	// Inline this once the minimum supported version is go1.22
@@ -766,26 +1545,16 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 
 	switch t := in.(type) {
 	case *gotypes.Struct:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
 		}
 		out.Kind = types.Struct
-		for i := 0; i < t.NumFields(); i++ {
-			f := t.Field(i)
-			m := types.Member{
-				Name:         f.Name(),
-				Embedded:     f.Anonymous(),
-				Tags:         t.Tag(i),
-				Type:         p.walkType(u, nil, f.Type()),
-				CommentLines: p.docComment(f.Pos()),
-			}
-			out.Members = append(out.Members, m)
-		}
+		out.Members = p.walkMembers(u, t)
 		return out
 	case *gotypes.Map:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
@@ -795,7 +1564,7 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 		out.Key = p.walkType(u, nil, t.Key())
 		return out
 	case *gotypes.Pointer:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
@@ -804,7 +1573,7 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 		out.Elem = p.walkType(u, nil, t.Elem())
 		return out
 	case *gotypes.Slice:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
@@ -813,7 +1582,7 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 		out.Elem = p.walkType(u, nil, t.Elem())
 		return out
 	case *gotypes.Array:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
@@ -823,15 +1592,21 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 		out.Len = in.(*gotypes.Array).Len()
 		return out
 	case *gotypes.Chan:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
 		}
 		out.Kind = types.Chan
 		out.Elem = p.walkType(u, nil, t.Elem())
-		// TODO: need to store direction, otherwise raw type name
-		// cannot be properly written.
+		switch t.Dir() {
+		case gotypes.SendOnly:
+			out.ChanDir = types.SendOnly
+		case gotypes.RecvOnly:
+			out.ChanDir = types.RecvOnly
+		default:
+			out.ChanDir = types.SendRecv
+		}
 		return out
 	case *gotypes.Basic:
 		out := u.Type(types.Name{
@@ -845,7 +1620,7 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 		out.Kind = types.Unsupported
 		return out
 	case *gotypes.Signature:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
@@ -854,68 +1629,77 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 		out.Signature = p.convertSignature(u, t)
 		return out
 	case *gotypes.Interface:
-		out := u.Type(name)
+		out := p.lookupType(u, name, in, local)
 		out.GoType = in
 		if out.Kind != types.Unknown {
 			return out
 		}
 		out.Kind = types.Interface
+		out.EmbeddedInterfaces, out.Unions = p.walkInterfaceElements(u, t)
 		t.Complete()
 		for i := 0; i < t.NumMethods(); i++ {
 			if out.Methods == nil {
 				out.Methods = map[string]*types.Type{}
 			}
 			method := t.Method(i)
-			name := goNameToName(method.String())
+			name := methodName(method)
 			mt := p.walkType(u, &name, method.Type())
-			mt.CommentLines = p.docComment(method.Pos())
+			mt.Position = p.fset.Position(method.Pos())
+			mt.CommentLines, mt.CommentLinePositions = p.docComment(method.Pos())
+			mt.TrailingCommentLines, mt.TrailingCommentLinePositions = p.lineComment(method.Pos())
 			out.Methods[method.Name()] = mt
 		}
 		return out
 	case *gotypes.Named:
+		if t.TypeArgs().Len() != 0 {
+			if !p.isSelfReference(u, t) {
+				return p.walkInstance(u, t)
+			}
+			t = t.Origin()
+			in = t
+		}
+		name := genericName(t)
 		var out *types.Type
 		switch t.Underlying().(type) {
 		case *gotypes.Named, *gotypes.Basic, *gotypes.Map, *gotypes.Slice:
-			name := goNameToName(t.String())
 			out = u.Type(name)
 			out.GoType = in
 			if out.Kind != types.Unknown {
 				return out
 			}
 			out.Kind = types.Alias
-			out.Underlying = p.walkType(u, nil, t.Underlying())
-		case *gotypes.Struct, *gotypes.Interface:
-			name := goNameToName(t.String())
-			tpMap := map[string]*types.Type{}
 			if t.TypeParams().Len() != 0 {
-				// Remove generics, then readd them without the encoded
-				// type, e.g. Foo[T any] => Foo[T]
-				var tpNames []string
-				for i := 0; i < t.TypeParams().Len(); i++ {
-					tp := t.TypeParams().At(i)
-					tpName := tp.Obj().Name()
-					tpNames = append(tpNames, tpName)
-					tpMap[tpName] = p.walkType(u, nil, tp.Constraint())
-				}
-				name.Name = fmt.Sprintf("%s[%s]", strings.SplitN(name.Name, "[", 2)[0], strings.Join(tpNames, ","))
+				out.TypeParams, out.TypeParamList = p.walkTypeParams(u, t.TypeParams())
 			}
-
+			out.Underlying = p.walkType(u, nil, t.Underlying())
+		case *gotypes.Struct, *gotypes.Interface:
+			tpMap, tpList := p.walkTypeParams(u, t.TypeParams())
 			if out := u.Type(name); out.Kind != types.Unknown {
 				out.GoType = in
 				return out // short circuit if we've already made this.
 			}
 			out = p.walkType(u, &name, t.Underlying())
 			out.TypeParams = tpMap
+			out.TypeParamList = tpList
 		default:
 			// gotypes package makes everything "named" with an
 			// underlying anonymous type--we remove that annoying
 			// "feature" for users. This flattens those types
 			// together.
-			name := goNameToName(t.String())
 			if out := u.Type(name); out.Kind != types.Unknown {
 				return out // short circuit if we've already made this.
 			}
+			var tpMap map[string]*types.Type
+			var tpList []*types.Type
+			if t.TypeParams().Len() != 0 {
+				tpMap, tpList = p.walkTypeParams(u, t.TypeParams())
+			}
 			out = p.walkType(u, &name, t.Underlying())
+			out.TypeParams = tpMap
+			out.TypeParamList = tpList
+		}
+		if !out.Position.IsValid() {
+			out.Position = p.fset.Position(t.Obj().Pos())
 		}
 		// If the underlying type didn't already add methods, add them.
 		// (Interface types will have already added methods.)
@@ -925,22 +1709,16 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 					out.Methods = map[string]*types.Type{}
 				}
 				method := t.Method(i)
-				name := goNameToName(method.String())
+				name := methodName(method)
 				mt := p.walkType(u, &name, method.Type())
-				mt.CommentLines = p.docComment(method.Pos())
+				mt.Position = p.fset.Position(method.Pos())
+				mt.CommentLines, mt.CommentLinePositions = p.docComment(method.Pos())
 				out.Methods[method.Name()] = mt
 			}
 		}
 		return out
 	case *gotypes.TypeParam:
-		// DO NOT retrieve the type from the universe. The default type-param name is only the
-		// generic variable name. Ideally, it would be namespaced by package and struct but it is
-		// not. Thus, if we try to use the universe, we would start polluting it.
-		// e.g. if Foo[T] and Bar[T] exists, we'd mistakenly use the same type T for both.
-		return &types.Type{
-			Name: name,
-			Kind: types.TypeParam,
-		}
+		return p.walkTypeParam(u, t)
 	default:
 		out := u.Type(name)
 		out.GoType = in
@@ -955,11 +1733,16 @@ func (p *Parser) walkType(u types.Universe, useName *types.Name, in gotypes.Type
 
 func (p *Parser) addFunction(u types.Universe, useName *types.Name, in *gotypes.Func) *types.Type {
 	name := goFuncNameToName(in.String())
+	if tps := in.Type().(*gotypes.Signature).TypeParams(); tps.Len() != 0 {
+		// As for generic types, e.g. Foo[T any] => Foo[T].
+		name.Name = in.Name() + typeParamNames(tps)
+	}
 	if useName != nil {
 		name = *useName
 	}
 	out := u.Function(name)
 	out.Kind = types.DeclarationOf
+	out.Position = p.fset.Position(in.Pos())
 	out.Underlying = p.walkType(u, nil, in.Type())
 	return out
 }
@@ -971,6 +1754,8 @@ func (p *Parser) addVariable(u types.Universe, useName *types.Name, in *gotypes.
 	}
 	out := u.Variable(name)
 	out.Kind = types.DeclarationOf
+	out.Position = p.fset.Position(in.Pos())
+	out.TrailingCommentLines, out.TrailingCommentLinePositions = p.lineComment(in.Pos())
 	out.Underlying = p.walkType(u, nil, in.Type())
 	return out
 }
@@ -982,6 +1767,9 @@ func (p *Parser) addConstant(u types.Universe, useName *types.Name, in *gotypes.
 	}
 	out := u.Constant(name)
 	out.Kind = types.DeclarationOf
+	out.Position = p.fset.Position(in.Pos())
+	out.TrailingCommentLines, out.TrailingCommentLinePositions = p.lineComment(in.Pos())
+	out.ConstDecl = p.constDecls[in.Pos()]
 	out.Underlying = p.walkType(u, nil, in.Type())
 
 	var constval string
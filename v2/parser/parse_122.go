@@ -27,6 +27,11 @@ import (
 
 func (p *Parser) walkAliasType(u types.Universe, in gotypes.Type) *types.Type {
 	if t, isAlias := in.(*gotypes.Alias); isAlias {
+		if t.Obj() == gotypes.Universe.Lookup("any") {
+			// Keep the name "any" (e.g. for constraints), rather than
+			// "interface{}".
+			return p.walkType(u, &types.Name{Name: "any"}, gotypes.Unalias(t))
+		}
 		return p.walkType(u, nil, gotypes.Unalias(t))
 	}
 	return nil
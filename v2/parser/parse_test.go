@@ -23,14 +23,19 @@ import (
 	"go/parser"
 	"go/token"
 	gotypes "go/types"
+	"maps"
+	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/tools/go/packages"
+	"k8s.io/gengo/v2/codetags"
 	"k8s.io/gengo/v2/types"
 )
 
@@ -347,6 +352,145 @@ func TestLoadPackagesInternal(t *testing.T) {
 	}
 }
 
+func TestIncludeTestFiles(t *testing.T) {
+	const pkgPath = "k8s.io/gengo/v2/parser/testdata/with-tests"
+	const onlyTestsPath = "k8s.io/gengo/v2/parser/testdata/only-test-files"
+
+	parser := NewWithOptions(Options{IncludeTestFiles: true})
+	u := types.Universe{}
+	pkgs, err := parser.LoadPackagesTo(&u, "./testdata/with-tests", "./testdata/only-test-files")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.Path)
+	}
+	if want, got := []string{onlyTestsPath, pkgPath, pkgPath + "_test"}, slices.Sorted(slices.Values(paths)); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong packages: want %q, got %q", want, got)
+	}
+
+	pkg := u[pkgPath]
+	if want, got := []string{"file.go"}, baseNames(pkg.GoFiles); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong GoFiles: want %q, got %q", want, got)
+	}
+	if want, got := []string{"file_test.go"}, baseNames(pkg.TestGoFiles); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong TestGoFiles: want %q, got %q", want, got)
+	}
+	fixture := pkg.Types["Fixture"]
+	if fixture == nil || fixture.Members[0].Type != pkg.Types["Type"] {
+		t.Errorf("expected Fixture to be declared by the package, got %v", fixture)
+	}
+
+	ext := u[pkgPath+"_test"]
+	if ext == nil {
+		t.Fatalf("external test package not found")
+	}
+	if ext.Name != "withtests_test" || len(ext.GoFiles) != 0 {
+		t.Errorf("unexpected external test package %q with GoFiles %q", ext.Name, ext.GoFiles)
+	}
+	if ext.Dir != pkg.Dir {
+		t.Errorf("expected the external test package to be in %s, got %s", pkg.Dir, ext.Dir)
+	}
+	if want, got := []string{"external_test.go"}, baseNames(ext.TestGoFiles); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong TestGoFiles: want %q, got %q", want, got)
+	}
+	if fake := ext.Types["Fake"]; fake == nil || fake.Members[0].Type != fixture {
+		t.Errorf("expected Fake to embed Fixture, got %v", fake)
+	}
+
+	if x := u[onlyTestsPath].Variables["X"]; x == nil {
+		t.Errorf("expected variable X from a package with only test files")
+	}
+
+	// Without the option, test files are not loaded.
+	u = types.Universe{}
+	if _, err := New().LoadPackagesTo(&u, "./testdata/with-tests"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u[pkgPath].Types["Fixture"] != nil || u[pkgPath+"_test"] != nil || len(u[pkgPath].TestGoFiles) != 0 {
+		t.Errorf("expected test files not to be loaded")
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	const pkgPath = "k8s.io/gengo/v2/parser/testdata/basic"
+	dir, err := filepath.Abs("./testdata/basic")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parser := NewWithOptions(Options{
+		Overlay: map[string][]byte{
+			// An edit.
+			filepath.Join(dir, "file.go"): []byte("package foo\n\ntype Blah struct {\n\tC bool\n}\n"),
+			// A new file.
+			filepath.Join(dir, "new.go"): []byte("package foo\n\ntype New struct{}\n"),
+		},
+	})
+	u := types.Universe{}
+	if _, err := parser.LoadPackagesTo(&u, "./testdata/basic"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pkg := u[pkgPath]
+	if want, got := []string{"file.go", "new.go"}, baseNames(pkg.GoFiles); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong GoFiles: want %q, got %q", want, got)
+	}
+	if blah := pkg.Types["Blah"]; len(blah.Members) != 1 || blah.Members[0].Name != "C" {
+		t.Errorf("expected the overlaid Blah, got members %v", blah.Members)
+	}
+	if pkg.Types["New"] == nil {
+		t.Errorf("expected type New from the new file")
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	dir, err := filepath.Abs("./testdata/workspace")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parser := NewWithOptions(Options{
+		Dir: dir,
+		// GOFLAGS may be incompatible with workspaces (e.g. -modfile), and
+		// GOWORK may point elsewhere.
+		Env: append(os.Environ(), "GOFLAGS=", "GOWORK="),
+	})
+	u := types.Universe{}
+	if _, err := parser.LoadPackagesTo(&u, "example.com/b/...", "./a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for pkgPath, pkgDir := range map[string]string{
+		"example.com/a":     "a",
+		"example.com/b":     "b",
+		"example.com/b/lib": filepath.Join("b", "lib"),
+	} {
+		pkg := u[pkgPath]
+		if pkg == nil {
+			t.Errorf("package %s not found", pkgPath)
+			continue
+		}
+		if want, got := filepath.Join(dir, pkgDir), pkg.Dir; want != got {
+			t.Errorf("wrong dir for %s: want %s, got %s", pkgPath, want, got)
+		}
+	}
+
+	user := u.Type(types.Name{Package: "example.com/b", Name: "User"})
+	if user.Kind != types.Struct || user.Members[0].Type != u.Type(types.Name{Package: "example.com/a", Name: "Shared"}) {
+		t.Errorf("expected User to refer to the other module's Shared, got %v", user.Members)
+	}
+	if shared := user.Members[0].Type; shared.Kind != types.Struct || len(shared.Members) != 1 {
+		t.Errorf("expected Shared to be parsed from the workspace, got %v", shared)
+	}
+}
+
+func baseNames(paths []string) []string {
+	var out []string
+	for _, path := range paths {
+		out = append(out, filepath.Base(path))
+	}
+	return out
+}
+
 func TestLoadPackagesTo(t *testing.T) {
 	parser := New()
 	u := types.Universe{}
@@ -532,6 +676,9 @@ func TestAddOnePkgToUniverse(t *testing.T) {
 		if want, got := filepath.Dir(pd.GoFiles[0]), ud.Dir; want != got {
 			t.Errorf("expected .Dir %q, got %q", want, got)
 		}
+		if want, got := pd.GoFiles, ud.GoFiles; !sliceEq(want, got) {
+			t.Errorf("expected .GoFiles %v, got %v", want, got)
+		}
 		if want, got := pi.PkgPath, ui.Path; want != got {
 			t.Errorf("expected .Path %q, got %q", want, got)
 		}
@@ -872,6 +1019,11 @@ func TestStructParse(t *testing.T) {
 				"./testdata/generic",
 			},
 			expected: func() *types.Type {
+				typeParamT := &types.Type{
+					Name:       types.Name{Name: "T"},
+					Kind:       types.TypeParam,
+					Constraint: types.Any,
+				}
 				return &types.Type{
 					Name: types.Name{
 						Package: "k8s.io/gengo/v2/parser/testdata/generic",
@@ -886,22 +1038,13 @@ func TestStructParse(t *testing.T) {
 							Embedded:     false,
 							CommentLines: []string{"V is the first field."},
 							Tags:         `json:"v"`,
-							Type: &types.Type{
-								Kind: types.TypeParam,
-								Name: types.Name{
-									Name: "T",
-								},
-							},
+							Type:         typeParamT,
 						},
 					},
 					TypeParams: map[string]*types.Type{
-						"T": {
-							Name: types.Name{
-								Name: "any",
-							},
-							Kind: types.Interface,
-						},
+						"T": types.Any,
 					},
+					TypeParamList: []*types.Type{typeParamT},
 				}
 			},
 		},
@@ -912,7 +1055,12 @@ func TestStructParse(t *testing.T) {
 				"./testdata/generic-field",
 			},
 			expected: func() *types.Type {
-				fieldType := &types.Type{
+				typeParamT := &types.Type{
+					Name:       types.Name{Name: "T"},
+					Kind:       types.TypeParam,
+					Constraint: types.Any,
+				}
+				genericType := &types.Type{
 					Name: types.Name{
 						Package: "k8s.io/gengo/v2/parser/testdata/generic-field",
 						Name:    "Blah[T]",
@@ -926,22 +1074,31 @@ func TestStructParse(t *testing.T) {
 							Embedded:     false,
 							CommentLines: []string{"V is the first field."},
 							Tags:         `json:"v"`,
-							Type: &types.Type{
-								Kind: types.TypeParam,
-								Name: types.Name{
-									Name: "T",
-								},
-							},
+							Type:         typeParamT,
 						},
 					},
 					TypeParams: map[string]*types.Type{
-						"T": {
-							Name: types.Name{
-								Name: "any",
-							},
-							Kind: types.Interface,
+						"T": types.Any,
+					},
+					TypeParamList: []*types.Type{typeParamT},
+				}
+				fieldType := &types.Type{
+					Name: types.Name{
+						Package: "k8s.io/gengo/v2/parser/testdata/generic-field",
+						Name:    "Blah[string]",
+					},
+					Kind: types.Struct,
+					Members: []types.Member{
+						{
+							Name:         "V",
+							Embedded:     false,
+							CommentLines: []string{"V is the first field."},
+							Tags:         `json:"v"`,
+							Type:         types.String,
 						},
 					},
+					Origin:   genericType,
+					TypeArgs: []*types.Type{types.String},
 				}
 				return &types.Type{
 					Name: types.Name{
@@ -970,6 +1127,14 @@ func TestStructParse(t *testing.T) {
 				"./testdata/generic-multi",
 			},
 			expected: func() *types.Type {
+				typeParam := func(name string) *types.Type {
+					return &types.Type{
+						Name:       types.Name{Name: name},
+						Kind:       types.TypeParam,
+						Constraint: types.Any,
+					}
+				}
+				typeParamT, typeParamU, typeParamV := typeParam("T"), typeParam("U"), typeParam("V")
 				return &types.Type{
 					Name: types.Name{
 						Package: "k8s.io/gengo/v2/parser/testdata/generic-multi",
@@ -984,58 +1149,29 @@ func TestStructParse(t *testing.T) {
 							Embedded:     false,
 							CommentLines: []string{"V1 is the first field."},
 							Tags:         `json:"v1"`,
-							Type: &types.Type{
-								Kind: types.TypeParam,
-								Name: types.Name{
-									Name: "T",
-								},
-							},
+							Type:         typeParamT,
 						},
 						{
 							Name:         "V2",
 							Embedded:     false,
 							CommentLines: []string{"V2 is the second field."},
 							Tags:         `json:"v2"`,
-							Type: &types.Type{
-								Kind: types.TypeParam,
-								Name: types.Name{
-									Name: "U",
-								},
-							},
+							Type:         typeParamU,
 						},
 						{
 							Name:         "V3",
 							Embedded:     false,
 							CommentLines: []string{"V3 is the third field."},
 							Tags:         `json:"v3"`,
-							Type: &types.Type{
-								Kind: types.TypeParam,
-								Name: types.Name{
-									Name: "V",
-								},
-							},
+							Type:         typeParamV,
 						},
 					},
 					TypeParams: map[string]*types.Type{
-						"T": {
-							Name: types.Name{
-								Name: "any",
-							},
-							Kind: types.Interface,
-						},
-						"U": {
-							Name: types.Name{
-								Name: "any",
-							},
-							Kind: types.Interface,
-						},
-						"V": {
-							Name: types.Name{
-								Name: "any",
-							},
-							Kind: types.Interface,
-						},
+						"T": types.Any,
+						"U": types.Any,
+						"V": types.Any,
 					},
+					TypeParamList: []*types.Type{typeParamT, typeParamU, typeParamV},
 				}
 			},
 		},
@@ -1045,6 +1181,12 @@ func TestStructParse(t *testing.T) {
 				"./testdata/generic-recursive",
 			},
 			expected: func() *types.Type {
+				// DeepCopyable's own T.
+				interfaceT := &types.Type{
+					Name:       types.Name{Name: "T"},
+					Kind:       types.TypeParam,
+					Constraint: types.Any,
+				}
 				recursiveT := &types.Type{
 					Name: types.Name{
 						Package: "k8s.io/gengo/v2/parser/testdata/generic-recursive",
@@ -1055,13 +1197,9 @@ func TestStructParse(t *testing.T) {
 					SecondClosestCommentLines: nil,
 					Methods:                   map[string]*types.Type{},
 					TypeParams: map[string]*types.Type{
-						"T": {
-							Name: types.Name{
-								Name: "any",
-							},
-							Kind: types.Interface,
-						},
+						"T": types.Any,
 					},
+					TypeParamList: []*types.Type{interfaceT},
 				}
 				recursiveT.Methods["DeepCopy"] = &types.Type{
 					Name: types.Name{
@@ -1074,16 +1212,44 @@ func TestStructParse(t *testing.T) {
 						Results: []*types.ParamResult{
 							{
 								Name: "",
-								Type: &types.Type{
-									Name: types.Name{
-										Name: "T",
-									},
-									Kind: types.TypeParam,
-								},
+								Type: interfaceT,
+							},
+						},
+					},
+				}
+
+				// Blah's T, which is constrained by DeepCopyable[T].
+				structT := &types.Type{
+					Name: types.Name{Name: "T"},
+					Kind: types.TypeParam,
+				}
+				constraint := &types.Type{
+					Name: types.Name{
+						Package: "k8s.io/gengo/v2/parser/testdata/generic-recursive",
+						Name:    "DeepCopyable[T]",
+					},
+					Kind:     types.Interface,
+					Methods:  map[string]*types.Type{},
+					Origin:   recursiveT,
+					TypeArgs: []*types.Type{structT},
+				}
+				constraint.Methods["DeepCopy"] = &types.Type{
+					Name: types.Name{
+						Name: "func (k8s.io/gengo/v2/parser/testdata/generic-recursive.DeepCopyable[T]).DeepCopy() T",
+					},
+					Kind: types.Func,
+					Signature: &types.Signature{
+						Receiver: constraint,
+						Results: []*types.ParamResult{
+							{
+								Name: "",
+								Type: structT,
 							},
 						},
 					},
 				}
+				structT.Constraint = constraint
+
 				return &types.Type{
 					Name: types.Name{
 						Package: "k8s.io/gengo/v2/parser/testdata/generic-recursive",
@@ -1098,17 +1264,13 @@ func TestStructParse(t *testing.T) {
 							Embedded:     false,
 							CommentLines: []string{"V is the first field."},
 							Tags:         `json:"v"`,
-							Type: &types.Type{
-								Name: types.Name{
-									Name: "T",
-								},
-								Kind: types.TypeParam,
-							},
+							Type:         structT,
 						},
 					},
 					TypeParams: map[string]*types.Type{
-						"T": recursiveT,
+						"T": constraint,
 					},
+					TypeParamList: []*types.Type{structT},
 				}
 			},
 		},
@@ -1183,6 +1345,13 @@ func TestStructParse(t *testing.T) {
 			}
 			opts := []cmp.Option{
 				cmpopts.IgnoreFields(types.Type{}, "GoType"),
+				// Positions are tested in TestPositions.
+				cmpopts.IgnoreFields(types.Type{}, "Position", "CommentLinePositions", "SecondClosestCommentLinePositions"),
+				cmpopts.IgnoreFields(types.Member{}, "Position", "CommentLinePositions"),
+				// Method sets are tested in TestMethodSets.
+				cmpopts.IgnoreFields(types.Type{}, "MethodSet", "PointerMethodSet"),
+				// Declaration order is tested in TestDeclarationOrder.
+				cmpopts.IgnoreFields(types.Type{}, "DeclarationOrder"),
 			}
 			if e, a := expected, st; !cmp.Equal(e, a, opts...) {
 				t.Errorf("wanted, got:\n%#v\n%#v\n%s", e, a, cmp.Diff(e, a, opts...))
@@ -1265,3 +1434,601 @@ func goTypeAliasEnabled() bool {
 	_, enabled := pkg.Scope().Lookup("A").Type().(*gotypes.Alias)
 	return enabled
 }
+
+// parseTestdata parses the named package under ./testdata, and returns the
+// Universe and the package.
+func parseTestdata(t *testing.T, name string) (types.Universe, *types.Package) {
+	t.Helper()
+	parser := New()
+	if _, err := parser.loadPackages("./testdata/" + name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, err := parser.NewUniverse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pkgPath := "k8s.io/gengo/v2/parser/testdata/" + name
+	pkg := u[pkgPath]
+	if pkg == nil {
+		t.Fatalf("package %s not found", pkgPath)
+	}
+	return u, pkg
+}
+
+func TestPositions(t *testing.T) {
+	_, pkg := parseTestdata(t, "positions")
+
+	// Just file, line and column.
+	pos := func(p token.Position) string {
+		return fmt.Sprintf("%s:%d:%d", filepath.Base(p.Filename), p.Line, p.Column)
+	}
+	positions := func(ps []token.Position) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, pos(p))
+		}
+		return out
+	}
+
+	blah := pkg.Types["Blah"]
+	if want, got := "file.go:9:6", pos(blah.Position); want != got {
+		t.Errorf("wrong type position: want %s, got %s", want, got)
+	}
+	// The //go:generate directive is not part of the comment text.
+	if want, got := []string{"Blah is a test.", "", "A test, I tell you."}, blah.CommentLines; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong type comments: want %q, got %q", want, got)
+	}
+	if want, got := []string{"file.go:5:1", "file.go:6:1", "file.go:8:1"}, positions(blah.CommentLinePositions); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong type comment positions: want %v, got %v", want, got)
+	}
+	if want, got := []string{"file.go:3:1"}, positions(blah.SecondClosestCommentLinePositions); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong detached comment positions: want %v, got %v", want, got)
+	}
+
+	if len(blah.Members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(blah.Members))
+	}
+	a, b := blah.Members[0], blah.Members[1]
+	if want, got := "file.go:11:2", pos(a.Position); want != got {
+		t.Errorf("wrong position for A: want %s, got %s", want, got)
+	}
+	if want, got := []string{"file.go:10:2"}, positions(a.CommentLinePositions); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong comment positions for A: want %v, got %v", want, got)
+	}
+	if want, got := "file.go:15:2", pos(b.Position); want != got {
+		t.Errorf("wrong position for B: want %s, got %s", want, got)
+	}
+	if want, got := []string{" B is the", "\t   second field."}, b.CommentLines; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong comments for B: want %q, got %q", want, got)
+	}
+	if want, got := []string{"file.go:13:2", "file.go:14:1"}, positions(b.CommentLinePositions); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong comment positions for B: want %v, got %v", want, got)
+	}
+
+	method := blah.Methods["Method"]
+	if want, got := "file.go:19:13", pos(method.Position); want != got {
+		t.Errorf("wrong method position: want %s, got %s", want, got)
+	}
+	if want, got := []string{"file.go:18:1"}, positions(method.CommentLinePositions); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong method comment positions: want %v, got %v", want, got)
+	}
+
+	for _, tc := range []struct {
+		decl     *types.Type
+		expected string
+	}{
+		{pkg.Functions["Func"], "file.go:22:6"},
+		{pkg.Variables["Var"], "file.go:25:5"},
+		{pkg.Constants["Const"], "file.go:28:7"},
+	} {
+		if want, got := tc.expected, pos(tc.decl.Position); want != got {
+			t.Errorf("wrong position for %s: want %s, got %s", tc.decl.Name, want, got)
+		}
+		if want, got := tc.decl.Position.Line-1, tc.decl.CommentLinePositions[0].Line; want != got {
+			t.Errorf("wrong comment line for %s: want %d, got %d", tc.decl.Name, want, got)
+		}
+	}
+
+	if b.Type.Position.IsValid() {
+		t.Errorf("expected builtin types to have no position, got %v", b.Type.Position)
+	}
+}
+
+func TestTrailingComments(t *testing.T) {
+	_, pkg := parseTestdata(t, "trailing-comments")
+
+	members := map[string]types.Member{}
+	for _, m := range pkg.Types["Struct"].Members {
+		members[m.Name] = m
+	}
+	for _, m := range members["Nested"].Type.Members {
+		members["Nested."+m.Name] = m
+	}
+	for _, m := range pkg.Types["OneLine"].Members {
+		members["OneLine."+m.Name] = m
+	}
+	for _, tc := range []struct {
+		member   string
+		expected []string
+	}{
+		{"Doc", []string{"+k8s:optional"}},
+		{"First", []string{"+k8s:required"}},
+		{"Second", []string{"+k8s:required"}},
+		{"Embedded", []string{"+k8s:embedded"}},
+		{"None", nil},
+		{"Inline", []string{"+k8s:inline"}},
+		{"Nested", nil},
+		{"Nested.C", []string{"+k8s:nested"}},
+		{"OneLine.A", nil},
+	} {
+		m, found := members[tc.member]
+		if !found {
+			t.Errorf("member %s not found", tc.member)
+			continue
+		}
+		if want, got := tc.expected, m.TrailingCommentLines; !reflect.DeepEqual(want, got) {
+			t.Errorf("wrong trailing comments for %s: want %q, got %q", tc.member, want, got)
+		}
+		if want, got := len(tc.expected), len(m.TrailingCommentLinePositions); want != got {
+			t.Errorf("wrong number of trailing comment positions for %s: want %d, got %d", tc.member, want, got)
+		} else if got > 0 && m.TrailingCommentLinePositions[0].Line != m.Position.Line {
+			t.Errorf("expected the trailing comment of %s to be on line %d, got %v", tc.member, m.Position.Line, m.TrailingCommentLinePositions[0])
+		}
+	}
+	// Doc comments are kept apart.
+	if want, got := []string{"Doc is documented."}, members["Doc"].CommentLines; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong comments for Doc: want %q, got %q", want, got)
+	}
+	if want, got := map[string][]string{"optional": {"optional"}}, codetags.Extract("+k8s:", members["Doc"].TrailingCommentLines); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong tags for Doc: want %v, got %v", want, got)
+	}
+
+	iface := pkg.Types["Interface"]
+	for _, tc := range []struct {
+		method   string
+		expected []string
+	}{
+		{"Method", []string{"+k8s:method"}},
+		{"Other", []string{" one", " two"}},
+	} {
+		if want, got := tc.expected, iface.Methods[tc.method].TrailingCommentLines; !reflect.DeepEqual(want, got) {
+			t.Errorf("wrong trailing comments for %s: want %q, got %q", tc.method, want, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		decl     *types.Type
+		expected []string
+	}{
+		{pkg.Constants["A"], []string{"+k8s:enumValue=first"}},
+		{pkg.Constants["B"], nil},
+		{pkg.Constants["C"], []string{"+k8s:enumValue=both"}},
+		{pkg.Constants["D"], []string{"+k8s:enumValue=both"}},
+		{pkg.Variables["V"], []string{"+k8s:var"}},
+	} {
+		if want, got := tc.expected, tc.decl.TrailingCommentLines; !reflect.DeepEqual(want, got) {
+			t.Errorf("wrong trailing comments for %s: want %q, got %q", tc.decl.Name, want, got)
+		}
+	}
+	if want, got := []string{"A is the first value."}, pkg.Constants["A"].CommentLines; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong comments for A: want %q, got %q", want, got)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	_, pkg := parseTestdata(t, "enums")
+
+	type value struct {
+		Name     string
+		Value    string
+		Iota     int
+		UsesIota bool
+	}
+	enums := map[string][][]value{}
+	var order []string
+	for _, e := range pkg.Enums() {
+		order = append(order, e.Type.Name.String())
+		for _, block := range e.Blocks {
+			var values []value
+			for _, c := range block {
+				values = append(values, value{c.Name.Name, *c.ConstValue, c.ConstDecl.Iota, c.ConstDecl.UsesIota})
+			}
+			enums[e.Type.Name.String()] = append(enums[e.Type.Name.String()], values)
+		}
+	}
+	if want, got := []string{pkg.Path + ".Color", pkg.Path + ".Phase", "time.Duration"}, order; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong enums: want %q, got %q", want, got)
+	}
+	expected := map[string][][]value{
+		pkg.Path + ".Color": {
+			{{"Red", "0", 0, true}, {"Green", "1", 1, true}, {"Blue", "2", 2, true}},
+			{{"Black", "11", 1, true}, {"White", "20", 2, false}, {"Gray", "20", 3, false}},
+		},
+		pkg.Path + ".Phase": {
+			{{"Pending", "Pending", 0, false}, {"Running", "Running", 1, false}, {"Succeeded", "Succeeded", 3, false}},
+			{{"Failed", "Failed", 0, false}},
+		},
+		"time.Duration": {
+			{{"Timeout", "5000000000", 0, false}},
+		},
+	}
+	if diff := cmp.Diff(expected, enums); diff != "" {
+		t.Errorf("wrong enum values (-want +got):\n%s", diff)
+	}
+
+	// Comments are kept.
+	red := pkg.Enums()[0].Values()[0]
+	if want, got := []string{"Red is the first color."}, red.CommentLines; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong comments for Red: want %q, got %q", want, got)
+	}
+	if want, got := []string{"+k8s:default"}, red.TrailingCommentLines; !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong trailing comments for Red: want %q, got %q", want, got)
+	}
+}
+
+func TestDeclarationOrder(t *testing.T) {
+	u, pkg := parseTestdata(t, "enums")
+
+	var decls []*types.Type
+	for _, m := range []map[string]*types.Type{pkg.Types, pkg.Functions, pkg.Variables, pkg.Constants} {
+		decls = slices.AppendSeq(decls, maps.Values(m))
+	}
+	slices.SortFunc(decls, func(a, b *types.Type) int {
+		return a.DeclarationOrder - b.DeclarationOrder
+	})
+	var names []string
+	for _, decl := range decls {
+		names = append(names, decl.Name.Name)
+	}
+	// The files are a.go and then b.go.
+	expected := []string{
+		"Color", "Red", "Green", "Blue",
+		"Phase", "Pending", "Running", "Untyped", "Succeeded",
+		"Timeout",
+		"Failed", "Black", "White", "Gray",
+	}
+	if !reflect.DeepEqual(expected, names) {
+		t.Errorf("wrong declaration order: want %q, got %q", expected, names)
+	}
+	for i, decl := range decls {
+		if decl.DeclarationOrder != i+1 {
+			t.Errorf("expected %s to be declaration %d, got %d", decl.Name, i+1, decl.DeclarationOrder)
+		}
+	}
+
+	// Types which the package doesn't declare are not numbered.
+	if d := u.Type(types.Name{Package: "time", Name: "Duration"}).DeclarationOrder; d != 0 {
+		t.Errorf("expected time.Duration not to be numbered by this package, got %d", d)
+	}
+}
+
+func TestChanDir(t *testing.T) {
+	_, pkg := parseTestdata(t, "chans")
+	chans := pkg.Types["Chans"]
+	if chans == nil {
+		t.Fatalf("type Chans not found")
+	}
+
+	expected := map[string][]types.ChanDir{
+		"Both":     {types.SendRecv},
+		"Send":     {types.SendOnly},
+		"Recv":     {types.RecvOnly},
+		"BothRecv": {types.SendRecv, types.RecvOnly},
+		"SendRecv": {types.SendOnly, types.RecvOnly},
+		"RecvBoth": {types.RecvOnly, types.SendRecv},
+	}
+	for _, m := range chans.Members {
+		var dirs []types.ChanDir
+		for t := m.Type; t.Kind == types.Chan; t = t.Elem {
+			dirs = append(dirs, t.ChanDir)
+		}
+		if want, got := expected[m.Name], dirs; !reflect.DeepEqual(want, got) {
+			t.Errorf("wrong directions for %s: want %q, got %q", m.Name, want, got)
+		}
+	}
+	if chans.Members[0].Type == chans.Members[1].Type || chans.Members[1].Type == chans.Members[2].Type {
+		t.Errorf("expected channels with different directions to be different types")
+	}
+}
+
+func TestInterfaceElements(t *testing.T) {
+	_, pkg := parseTestdata(t, "interfaces")
+
+	unions := func(typ *types.Type) []string {
+		var out []string
+		for _, union := range typ.Unions {
+			var terms []string
+			for _, term := range union {
+				terms = append(terms, term.String())
+			}
+			out = append(out, strings.Join(terms, " | "))
+		}
+		return out
+	}
+	embeddeds := func(typ *types.Type) []string {
+		var out []string
+		for _, e := range typ.EmbeddedInterfaces {
+			if e.Kind != types.Interface {
+				t.Errorf("expected embedded %v to be an interface, got %s", e, e.Kind)
+			}
+			out = append(out, e.String())
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name      string
+		typ       *types.Type
+		embeddeds []string
+		unions    []string
+		methods   []string
+	}{{
+		name:   "Integer",
+		typ:    pkg.Types["Integer"],
+		unions: []string{"~int | ~int32 | ~int64"},
+	}, {
+		name:      "Number",
+		typ:       pkg.Types["Number"],
+		embeddeds: []string{pkg.Path + ".Integer"},
+		unions:    []string{"~float64 | " + pkg.Path + ".MyInt"},
+	}, {
+		name:      "Named",
+		typ:       pkg.Types["Named"],
+		embeddeds: []string{"fmt.Stringer", "comparable"},
+		methods:   []string{"Name", "String"},
+	}, {
+		name:   "Exact",
+		typ:    pkg.Types["Exact"],
+		unions: []string{"int"},
+	}, {
+		name:   "constraint of Container",
+		typ:    pkg.Types["Container[T]"].TypeParamList[0].Constraint,
+		unions: []string{"~int | ~int32 | ~int64"},
+	}, {
+		name:   "inline constraint of Inline",
+		typ:    pkg.Types["Inline[T]"].TypeParamList[0].Constraint,
+		unions: []string{"~string | fmt.Stringer"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.typ.Kind != types.Interface {
+				t.Fatalf("expected an interface, got %s", tc.typ.Kind)
+			}
+			if want, got := tc.embeddeds, embeddeds(tc.typ); !reflect.DeepEqual(want, got) {
+				t.Errorf("wrong embedded interfaces: want %q, got %q", want, got)
+			}
+			if want, got := tc.unions, unions(tc.typ); !reflect.DeepEqual(want, got) {
+				t.Errorf("wrong unions: want %q, got %q", want, got)
+			}
+			if want, got := tc.methods, slices.Sorted(maps.Keys(tc.typ.Methods)); !reflect.DeepEqual(want, got) {
+				t.Errorf("wrong methods: want %q, got %q", want, got)
+			}
+		})
+	}
+
+	// Terms refer to the parsed types.
+	number := pkg.Types["Number"]
+	if number.EmbeddedInterfaces[0] != pkg.Types["Integer"] || number.Unions[0][1].Type != pkg.Types["MyInt"] {
+		t.Errorf("expected Number to refer to Integer and MyInt")
+	}
+}
+
+func TestMethodSets(t *testing.T) {
+	u, pkg := parseTestdata(t, "methodsets")
+
+	// Each method is described as "name kind path".
+	describe := func(ms []*types.MethodSelection) []string {
+		var out []string
+		for _, m := range ms {
+			if m.Method == nil || m.Method.Kind != types.Func {
+				t.Errorf("method %s: expected a method, got %v", m.Name, m.Method)
+			}
+			out = append(out, strings.TrimSpace(fmt.Sprintf("%s %s %s", m.Name, m.ReceiverKind, strings.Join(m.Path, "."))))
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name             string
+		methodSet        []string
+		pointerMethodSet []string
+	}{{
+		name:             "Inner",
+		methodSet:        []string{"Value Value", "unexported Value"},
+		pointerMethodSet: []string{"Pointer Pointer", "Value Value", "unexported Value"},
+	}, {
+		name:             "Outer",
+		methodSet:        []string{"Value Value", "unexported Value Inner"},
+		pointerMethodSet: []string{"Pointer Pointer Inner", "Value Value", "unexported Value Inner"},
+	}, {
+		name:             "ByPointer",
+		methodSet:        []string{"Pointer Pointer Inner", "Value Value Inner", "unexported Value Inner"},
+		pointerMethodSet: []string{"Pointer Pointer Inner", "Value Value Inner", "unexported Value Inner"},
+	}, {
+		name:             "Deep",
+		methodSet:        []string{"Value Value Outer", "unexported Value Outer.Inner"},
+		pointerMethodSet: []string{"Pointer Pointer Outer.Inner", "Value Value Outer", "unexported Value Outer.Inner"},
+	}, {
+		name:             "WithInterface",
+		methodSet:        []string{"String Interface Stringer"},
+		pointerMethodSet: []string{"String Interface Stringer"},
+	}, {
+		name:      "Iface",
+		methodSet: []string{"Do Interface", "String Interface"},
+	}, {
+		name:             "Generic[T]",
+		pointerMethodSet: []string{"Get Pointer"},
+	}, {
+		name:             "Instance",
+		pointerMethodSet: []string{"Get Pointer Generic"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			typ := pkg.Types[tc.name]
+			if typ == nil {
+				t.Fatalf("type %s not found", tc.name)
+			}
+			if want, got := tc.methodSet, describe(typ.MethodSet); !reflect.DeepEqual(want, got) {
+				t.Errorf("wrong method set: want %q, got %q", want, got)
+			}
+			if want, got := tc.pointerMethodSet, describe(typ.PointerMethodSet); !reflect.DeepEqual(want, got) {
+				t.Errorf("wrong pointer method set: want %q, got %q", want, got)
+			}
+		})
+	}
+
+	// Methods refer to their declarations.
+	inner, outer, deep := pkg.Types["Inner"], pkg.Types["Outer"], pkg.Types["Deep"]
+	if m := deep.LookupMethod("Value", false); m == nil || m.Method != outer.Methods["Value"] {
+		t.Errorf("expected Deep.Value to be Outer.Value, got %v", m)
+	}
+	if m := deep.LookupMethod("Pointer", true); m == nil || m.Method != inner.Methods["Pointer"] {
+		t.Errorf("expected (*Deep).Pointer to be (*Inner).Pointer, got %v", m)
+	}
+	if m := deep.LookupMethod("Pointer", false); m != nil {
+		t.Errorf("expected Deep not to have Pointer, got %v", m)
+	}
+	stringer := u.Type(types.Name{Package: "fmt", Name: "Stringer"})
+	if m := pkg.Types["Iface"].LookupMethod("String", false); m == nil || m.Method != stringer.Methods["String"] {
+		t.Errorf("expected Iface.String to be fmt.Stringer.String, got %v", m)
+	}
+	if m := pkg.Types["Instance"].LookupMethod("Get", true); m == nil || m.Method != pkg.Types["Generic[T]"].Methods["Get"] {
+		t.Errorf("expected (*Instance).Get to be the method of Generic[T], got %v", m)
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	u, pkg := parseTestdata(t, "generic-decls")
+
+	// Instances are not declared by the package.
+	expectedTypes := []string{"Box[T]", "Instances", "List[T]", "Node[T]", "Number", "Other[T]", "Pair[K,V]", "Set[K]"}
+	if want, got := expectedTypes, slices.Sorted(maps.Keys(pkg.Types)); !reflect.DeepEqual(want, got) {
+		t.Errorf("wrong types: want %v, got %v", want, got)
+	}
+
+	typeParamNames := func(tps []*types.Type) []string {
+		var names []string
+		for _, tp := range tps {
+			if tp.Kind != types.TypeParam {
+				t.Errorf("%s: expected kind %s, got %s", tp, types.TypeParam, tp.Kind)
+			}
+			names = append(names, tp.Name.Name)
+		}
+		return names
+	}
+
+	list := pkg.Types["List[T]"]
+	if want, got := []string{"T"}, typeParamNames(list.TypeParamList); !reflect.DeepEqual(want, got) {
+		t.Fatalf("wrong type params for List: want %v, got %v", want, got)
+	}
+	listT := list.TypeParamList[0]
+	if listT.Constraint != types.Any {
+		t.Errorf("wrong constraint for List's T: %v", listT.Constraint)
+	}
+	if list.Kind != types.Alias || list.Underlying.Kind != types.Slice || list.Underlying.Elem != listT {
+		t.Errorf("expected List to be a slice of T, got %s of %v", list.Kind, list.Underlying)
+	}
+
+	set := pkg.Types["Set[K]"]
+	if want, got := []string{"K"}, typeParamNames(set.TypeParamList); !reflect.DeepEqual(want, got) {
+		t.Fatalf("wrong type params for Set: want %v, got %v", want, got)
+	}
+	if want, got := "comparable", set.TypeParamList[0].Constraint.Name.Name; want != got {
+		t.Errorf("wrong constraint for Set's K: want %s, got %s", want, got)
+	}
+	if set.TypeParams["K"] != set.TypeParamList[0].Constraint {
+		t.Errorf("expected TypeParams to map to the constraints")
+	}
+
+	node := pkg.Types["Node[T]"]
+	if next := node.Members[1].Type; next.Kind != types.Pointer || next.Elem != node {
+		t.Errorf("expected Node's Next to point to Node itself, got %v", next)
+	}
+
+	pair := pkg.Types["Pair[K,V]"]
+	if want, got := []string{"K", "V"}, typeParamNames(pair.TypeParamList); !reflect.DeepEqual(want, got) {
+		t.Fatalf("wrong type params for Pair: want %v, got %v", want, got)
+	}
+	if pair.TypeParamList[1].Constraint != pkg.Types["Number"] {
+		t.Errorf("wrong constraint for Pair's V: %v", pair.TypeParamList[1].Constraint)
+	}
+	// The method's receiver type parameters (X and Y) are Pair's.
+	swap := pair.Methods["Swap"].Signature
+	if !reflect.DeepEqual(pair.TypeParamList, swap.TypeParams) {
+		t.Errorf("expected the method's type params to be Pair's, got %v", swap.TypeParams)
+	}
+	if swap.Parameters[0].Type != pair.TypeParamList[1] || swap.Results[0].Type != pair.TypeParamList[1] {
+		t.Errorf("expected the method to use Pair's V")
+	}
+	if swap.Receiver.Kind != types.Pointer || swap.Receiver.Elem != pair {
+		t.Errorf("expected the method's receiver to be *Pair, got %v", swap.Receiver)
+	}
+
+	// []T in Other must not be confused with []T in List.
+	other := pkg.Types["Other[T]"]
+	if values := other.Members[0].Type; values.Elem != other.TypeParamList[0] {
+		t.Errorf("expected Other's Values to be a slice of Other's T")
+	}
+
+	instances := pkg.Types["Instances"]
+	ints := instances.Members[0].Type
+	if ints.Origin != list || !reflect.DeepEqual(ints.TypeArgs, []*types.Type{types.Int}) {
+		t.Errorf("expected List[int], got %v (origin %v, args %v)", ints, ints.Origin, ints.TypeArgs)
+	}
+	if ints.Kind != types.Alias || ints.Underlying.Elem != types.Int {
+		t.Errorf("expected List[int] to be a slice of int, got %s of %v", ints.Kind, ints.Underlying)
+	}
+	if want, got := "List[int]", ints.Name.Name; want != got {
+		t.Errorf("wrong name for List[int]: want %s, got %s", want, got)
+	}
+	pairs := instances.Members[1].Type
+	if len(pairs.TypeArgs) != 1 || pairs.TypeArgs[0].Origin != pair {
+		t.Fatalf("expected List[Pair[string, int]], got %v", pairs)
+	}
+	if value := pairs.TypeArgs[0].Members[1].Type; value != types.Int {
+		t.Errorf("expected Pair[string, int]'s Value to be an int, got %v", value)
+	}
+	// Instantiating the same type gets the parsed instance, with its methods.
+	pairOfStringInt, err := u.Instantiate(pair, types.String, types.Int)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pairOfStringInt != pairs.TypeArgs[0] {
+		t.Errorf("expected the parsed Pair[string, int], got %p, want %p", pairOfStringInt, pairs.TypeArgs[0])
+	}
+	if swap := pairOfStringInt.Methods["Swap"]; swap == nil {
+		t.Errorf("expected Pair[string, int] to have method Swap")
+	} else if sig := swap.Signature; sig.Receiver.Elem != pairOfStringInt || sig.Parameters[0].Type != types.Int || len(sig.TypeParams) != 0 {
+		t.Errorf("wrong signature for Pair[string, int].Swap: %#v", sig)
+	}
+	// Including anonymous interfaces, whose methods' receiver is the
+	// interface itself.
+	boxOfInt, err := u.Instantiate(pkg.Types["Box[T]"], types.Int)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := boxOfInt.Members[0].Type
+	if want, got := "interface{Get() int; Set(int) bool}", g.Name.Name; want != got {
+		t.Errorf("wrong name for Box[int]'s G: want %q, got %q", want, got)
+	}
+	if get := g.Methods["Get"]; get.Signature.Receiver != g || get.Signature.Results[0].Type != types.Int {
+		t.Errorf("wrong signature for Box[int]'s G.Get: %#v", get.Signature)
+	}
+	nodeOfInstances := instances.Members[2].Type.Elem
+	if nodeOfInstances.Origin != node || nodeOfInstances.Members[0].Type != instances {
+		t.Errorf("expected Node[Instances], got %v", nodeOfInstances)
+	}
+	if next := nodeOfInstances.Members[1].Type; next.Elem != nodeOfInstances {
+		t.Errorf("expected Node[Instances]'s Next to point to Node[Instances], got %v", next)
+	}
+
+	fn := pkg.Functions["Map[T,U]"]
+	if fn == nil {
+		t.Fatalf("function Map[T,U] not found in %v", slices.Collect(maps.Keys(pkg.Functions)))
+	}
+	sig := fn.Underlying.Signature
+	if want, got := []string{"T", "U"}, typeParamNames(sig.TypeParams); !reflect.DeepEqual(want, got) {
+		t.Fatalf("wrong type params for Map: want %v, got %v", want, got)
+	}
+	if sig.Parameters[0].Type.Elem != sig.TypeParams[0] || sig.Results[0].Type.Elem != sig.TypeParams[1] {
+		t.Errorf("expected Map to use its own type params")
+	}
+}
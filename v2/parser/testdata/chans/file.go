@@ -0,0 +1,12 @@
+package chans
+
+type Elem struct{}
+
+type Chans struct {
+	Both     chan Elem
+	Send     chan<- Elem
+	Recv     <-chan Elem
+	BothRecv chan (<-chan Elem)
+	SendRecv chan<- <-chan Elem
+	RecvBoth <-chan chan Elem
+}
@@ -0,0 +1,28 @@
+package enums
+
+import "time"
+
+// Color is a color.
+type Color int
+
+const (
+	// Red is the first color.
+	Red Color = iota // +k8s:default
+	Green
+	Blue
+)
+
+// Phase is a phase.
+type Phase string
+
+const (
+	Pending Phase = "Pending"
+	Running Phase = "Running"
+
+	// Not a Phase.
+	Untyped = "untyped"
+
+	Succeeded Phase = "Succeeded"
+)
+
+const Timeout time.Duration = 5 * time.Second
@@ -0,0 +1,10 @@
+package enums
+
+const Failed Phase = "Failed"
+
+const (
+	_ Color = iota + 10
+	Black
+	White Color = 20
+	Gray
+)
@@ -0,0 +1,52 @@
+package foo
+
+type Number interface {
+	~int | ~int64
+}
+
+// List is a generic named slice.
+type List[T any] []T
+
+// Set is a generic named map.
+type Set[K comparable] map[K]struct{}
+
+// Node refers to itself.
+type Node[T any] struct {
+	Value T
+	Next  *Node[T]
+}
+
+// Pair has a method.
+type Pair[K comparable, V Number] struct {
+	Key   K
+	Value V
+}
+
+func (p *Pair[X, Y]) Swap(v Y) Y {
+	return v
+}
+
+// Other has a type parameter with the same name as Node's.
+type Other[T Number] struct {
+	Values []T
+}
+
+// Instances uses instances of the generic types.
+type Instances struct {
+	Ints  List[int]
+	Pairs List[Pair[string, int]]
+	Node  *Node[Instances]
+}
+
+// Map is a generic function.
+func Map[T, U any](in []T, f func(T) U) []U {
+	return nil
+}
+
+// Box has an anonymous interface which refers to its type parameter.
+type Box[T any] struct {
+	G interface {
+		Get() T
+		Set(T) bool
+	}
+}
@@ -0,0 +1,35 @@
+package interfaces
+
+import "fmt"
+
+type Integer interface {
+	~int | ~int32 | ~int64
+}
+
+type MyInt int
+
+// Number embeds another constraint.
+type Number interface {
+	Integer
+	~float64 | MyInt
+}
+
+// Named has embedded interfaces and methods of its own.
+type Named interface {
+	fmt.Stringer
+	comparable
+	Name() string
+}
+
+// Exact is a single type.
+type Exact interface {
+	int
+}
+
+type Container[T Integer] struct {
+	Items []T
+}
+
+type Inline[T interface{ ~string | fmt.Stringer }] struct {
+	Item T
+}
@@ -0,0 +1,50 @@
+package methodsets
+
+import "fmt"
+
+type Inner struct{}
+
+func (Inner) Value() string { return "" }
+
+func (*Inner) Pointer() {}
+
+func (Inner) unexported() {}
+
+// Outer embeds Inner by value.
+type Outer struct {
+	Inner
+}
+
+// Own shadows Inner.Value.
+func (Outer) Value() string { return "" }
+
+// ByPointer embeds Inner by pointer, so its method set includes Pointer.
+type ByPointer struct {
+	*Inner
+}
+
+// Deep promotes Inner's methods through two levels.
+type Deep struct {
+	Middle Outer
+	Outer
+}
+
+// WithInterface embeds an interface.
+type WithInterface struct {
+	fmt.Stringer
+}
+
+type Iface interface {
+	fmt.Stringer
+	Do()
+}
+
+type Generic[T any] struct {
+	Item T
+}
+
+func (g *Generic[T]) Get() T { return g.Item }
+
+type Instance struct {
+	Generic[int]
+}
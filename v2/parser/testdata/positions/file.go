@@ -0,0 +1,28 @@
+package positions
+
+// Detached comment.
+
+// Blah is a test.
+//
+//go:generate echo hi
+// A test, I tell you.
+type Blah struct {
+	// A is the first field.
+	A int64
+
+	/* B is the
+	   second field. */
+	B string
+}
+
+// Method is a method.
+func (Blah) Method() {}
+
+// Func is a function.
+func Func() {}
+
+// Var is a variable.
+var Var int
+
+// Const is a constant.
+const Const = 1
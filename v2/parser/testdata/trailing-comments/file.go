@@ -0,0 +1,46 @@
+package trailingcomments
+
+// Embedded is embedded.
+type Embedded struct{}
+
+// Struct has fields with trailing comments.
+type Struct struct {
+	// Doc is documented.
+	Doc string // +k8s:optional
+
+	First, Second int // +k8s:required
+
+	*Embedded // +k8s:embedded
+
+	None bool
+
+	Inline struct{ A, B int } // +k8s:inline
+
+	Nested struct {
+		C int // +k8s:nested
+	}
+}
+
+// OneLine is all on one line, so its fields have no trailing comments.
+type OneLine struct{ A int } // +k8s:oneline
+
+// Interface has methods with trailing comments.
+type Interface interface {
+	// Method is documented.
+	Method() // +k8s:method
+
+	Other() /* one */ /* two */
+}
+
+// Enum is a string.
+type Enum string
+
+const (
+	// A is the first value.
+	A Enum = "a" // +k8s:enumValue=first
+	B Enum = "b"
+
+	C, D Enum = "c", "d" // +k8s:enumValue=both
+)
+
+var V = 1 // +k8s:var
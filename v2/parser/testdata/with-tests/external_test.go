@@ -0,0 +1,7 @@
+package withtests_test
+
+import "k8s.io/gengo/v2/parser/testdata/with-tests"
+
+type Fake struct {
+	withtests.Fixture
+}
@@ -0,0 +1,5 @@
+package withtests
+
+type Type struct {
+	Name string
+}
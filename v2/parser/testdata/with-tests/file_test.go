@@ -0,0 +1,6 @@
+package withtests
+
+// Fixture is only declared for tests.
+type Fixture struct {
+	Type Type
+}
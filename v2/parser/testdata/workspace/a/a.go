@@ -0,0 +1,6 @@
+package a
+
+// Shared is used by another module in the workspace.
+type Shared struct {
+	Name string
+}
@@ -0,0 +1,11 @@
+package b
+
+import (
+	"example.com/a"
+	"example.com/b/lib"
+)
+
+type User struct {
+	Shared a.Shared
+	Lib    lib.Lib
+}
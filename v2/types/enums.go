@@ -0,0 +1,93 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Enum is a named type, and the constants of that type which a package
+// declares.
+type Enum struct {
+	// The type of the constants.
+	Type *Type
+
+	// The constants, grouped by the const declarations which declare them,
+	// in the order in which they are declared. Each constant has Kind ==
+	// DeclarationOf, and its value in ConstValue.
+	Blocks [][]*Type
+}
+
+// Values returns all of the constants of the enum, in the order in which
+// they are declared.
+func (e *Enum) Values() []*Type {
+	return slices.Concat(e.Blocks...)
+}
+
+// Enums finds, for each named type, the constants of that type which p
+// declares. The enums are sorted by the names of their types. Constants
+// which are not of a named type, e.g. untyped constants, are not included.
+func (p *Package) Enums() []*Enum {
+	var consts []*Type
+	for _, c := range p.Constants {
+		if t := c.Underlying; t != nil && t.Name.Package != "" {
+			consts = append(consts, c)
+		}
+	}
+	slices.SortFunc(consts, func(a, b *Type) int {
+		return cmp.Or(
+			strings.Compare(a.Position.Filename, b.Position.Filename),
+			cmp.Compare(a.Position.Offset, b.Position.Offset),
+			strings.Compare(a.Name.Name, b.Name.Name),
+		)
+	})
+
+	type blockKey struct {
+		typ   *Type
+		block any
+	}
+	enums := map[*Type]*Enum{}
+	blocks := map[blockKey]int{}
+	var out []*Enum
+	for _, c := range consts {
+		e := enums[c.Underlying]
+		if e == nil {
+			e = &Enum{Type: c.Underlying}
+			enums[c.Underlying] = e
+			out = append(out, e)
+		}
+		// Constants which don't say where they were declared are each
+		// in a block of their own.
+		key := blockKey{c.Underlying, c}
+		if c.ConstDecl != nil {
+			key.block = c.ConstDecl.Block
+		}
+		i, found := blocks[key]
+		if !found {
+			i = len(e.Blocks)
+			blocks[key] = i
+			e.Blocks = append(e.Blocks, nil)
+		}
+		e.Blocks[i] = append(e.Blocks[i], c)
+	}
+	slices.SortFunc(out, func(a, b *Enum) int {
+		return strings.Compare(a.Type.Name.String(), b.Type.Name.String())
+	})
+	return out
+}
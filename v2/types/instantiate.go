@@ -0,0 +1,493 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Instance returns the canonical instance of a generic type with the given
+// type arguments, e.g. List[Pod] for List[T] and Pod. If a non-existing
+// instance is requested, this will create (a marker for) it, with its Name,
+// Origin and TypeArgs set. If a marker is created, it's the caller's
+// responsibility to finish construction of the instance; see Instantiate,
+// which does that.
+//
+// If any of the type arguments refer to type parameters (e.g. List[U] within
+// the declaration of Foo[U]), the instance is not kept in the Universe, since
+// type parameters are only named by their own names, and a new marker is
+// returned every time.
+//
+// This modifies the Universe, so it must not be called while targets are
+// executed concurrently (see generator.Context.Parallelism).
+func (u Universe) Instance(generic *Type, args ...*Type) *Type {
+	argNames := make([]string, 0, len(args))
+	local := false
+	for _, arg := range args {
+		argNames = append(argNames, arg.String())
+		local = local || mentionsTypeParams(arg)
+	}
+	baseName, _, _ := strings.Cut(generic.Name.Name, "[")
+	name := Name{Package: generic.Name.Package, Name: baseName + "[" + strings.Join(argNames, ", ") + "]"}
+	if local {
+		return &Type{Name: name, Origin: generic, TypeArgs: args}
+	}
+
+	p := u.Package(name.Package)
+	if p.Instances == nil {
+		p.Instances = map[string]*Type{}
+	}
+	if t, ok := p.Instances[name.Name]; ok {
+		return t
+	}
+	t := &Type{Name: name, Origin: generic, TypeArgs: args}
+	p.Instances[name.Name] = t
+	return t
+}
+
+// Instantiate returns the instance of a generic type with the given type
+// arguments, e.g. List[Pod] for List[T] and Pod. The instance's members,
+// elements, underlying type and method signatures are those of the generic
+// type, with each type parameter replaced by the corresponding type argument.
+// Types which refer to the type parameters are replaced in the same way, so
+// e.g. a member of type []T becomes []Pod, and one of type Node[T] becomes
+// Node[Pod].
+//
+// Instances are kept in the Universe (see Instance), so instantiating the
+// same generic type with the same type arguments returns the same Type, as
+// does referring to that instance in parsed code. The type arguments are
+// not checked against the type parameters' constraints.
+//
+// This modifies the Universe, so it must not be called while targets are
+// executed concurrently (see generator.Context.Parallelism).
+func (u Universe) Instantiate(generic *Type, args ...*Type) (*Type, error) {
+	if generic.Origin != nil {
+		return nil, fmt.Errorf("%v is already an instance of %v", generic, generic.Origin)
+	}
+	if len(generic.TypeParamList) == 0 {
+		return nil, fmt.Errorf("%v is not a generic type", generic)
+	}
+	if want, got := len(generic.TypeParamList), len(args); want != got {
+		return nil, fmt.Errorf("wrong number of type arguments for %v: want %d, got %d", generic, want, got)
+	}
+
+	out := u.instantiate(generic, args, map[string]*Type{})
+	// Other instances which are created along the way don't get their
+	// methods, which would otherwise go on forever for methods like
+	// func (l List[T]) Split() List[List[T]].
+	if out.Methods == nil && len(generic.Methods) != 0 {
+		s := u.substituter(generic, args, map[string]*Type{})
+		out.Methods = map[string]*Type{}
+		for name, m := range generic.Methods {
+			sig, _ := s.signature(m.Signature)
+			mt := &Type{
+				Kind:                         Func,
+				Position:                     m.Position,
+				CommentLines:                 m.CommentLines,
+				CommentLinePositions:         m.CommentLinePositions,
+				TrailingCommentLines:         m.TrailingCommentLines,
+				TrailingCommentLinePositions: m.TrailingCommentLinePositions,
+				Signature:                    sig,
+			}
+			mt.Name = Name{Name: "func (" + sig.Receiver.String() + ")." + name + strings.TrimPrefix(anonymousName(mt), "func")}
+			out.Methods[name] = mt
+		}
+	}
+	return out, nil
+}
+
+// instantiate returns the instance of generic with args, substituting
+// everything but its methods if it is new. Instances which are not kept in
+// the Universe are kept in local for the duration of the substitution, so
+// that e.g. List[T] referring to itself does not go on forever.
+func (u Universe) instantiate(generic *Type, args []*Type, local map[string]*Type) *Type {
+	key := fmt.Sprintf("%p", generic)
+	for _, arg := range args {
+		key += fmt.Sprintf(",%p", arg)
+	}
+	out, ok := local[key]
+	if !ok {
+		out = u.Instance(generic, args...)
+		local[key] = out
+	}
+	if out.Kind != Unknown {
+		return out
+	}
+	s := u.substituter(generic, args, local)
+	// Set the kind first, since the members etc. may refer to out.
+	out.Kind = generic.Kind
+	out.Position = generic.Position
+	out.Len = generic.Len
+	out.ChanDir = generic.ChanDir
+	for _, m := range generic.Members {
+		m.Type = s.typ(m.Type)
+		out.Members = append(out.Members, m)
+	}
+	out.Elem = s.typ(generic.Elem)
+	out.Key = s.typ(generic.Key)
+	out.Underlying = s.typ(generic.Underlying)
+	if generic.Signature != nil {
+		out.Signature, _ = s.signature(generic.Signature)
+	}
+	if generic.Kind == Interface {
+		// An interface's methods are part of the type itself.
+		out.Methods = map[string]*Type{}
+		for name, m := range generic.Methods {
+			out.Methods[name] = s.typ(m)
+		}
+		out.EmbeddedInterfaces, _ = s.types(generic.EmbeddedInterfaces)
+		out.Unions, _ = s.unions(generic.Unions)
+	}
+	return out
+}
+
+// substituter replaces type parameters with type arguments.
+type substituter struct {
+	u     Universe
+	args  map[*Type]*Type
+	local map[string]*Type
+}
+
+func (u Universe) substituter(generic *Type, args []*Type, local map[string]*Type) substituter {
+	s := substituter{u: u, args: map[*Type]*Type{}, local: local}
+	for i, tp := range generic.TypeParamList {
+		s.args[tp] = args[i]
+	}
+	return s
+}
+
+// typ returns t with the type parameters replaced, or t itself if it does
+// not refer to them.
+func (s substituter) typ(t *Type) *Type {
+	if t == nil {
+		return nil
+	}
+	if arg, ok := s.args[t]; ok {
+		return arg
+	}
+	switch {
+	case t.Kind == TypeParam:
+		return t
+	case t.Origin != nil:
+		args, changed := s.types(t.TypeArgs)
+		if !changed {
+			return t
+		}
+		return s.u.instantiate(t.Origin, args, s.local)
+	case len(t.TypeParamList) != 0:
+		// A generic type referring to itself, e.g. Node[T] within the
+		// declaration of Node[T].
+		args, changed := s.types(t.TypeParamList)
+		if !changed {
+			return t
+		}
+		return s.u.instantiate(t, args, s.local)
+	case t.Name.Package != "":
+		// Other named types can't refer to type parameters.
+		return t
+	}
+
+	// Only anonymous types remain.
+	switch t.Kind {
+	case Slice, Array, Pointer, Map, Chan:
+		elem, key := s.typ(t.Elem), s.typ(t.Key)
+		if elem == t.Elem && key == t.Key {
+			return t
+		}
+		c := &Type{Kind: t.Kind, Elem: elem, Key: key, Len: t.Len, ChanDir: t.ChanDir}
+		c.Name = Name{Name: anonymousName(c)}
+		if mentionsTypeParams(c) {
+			// As for Instance.
+			return c
+		}
+		// These are named just as the parser names them, so if it has
+		// already seen this type, that Type is reused.
+		out := s.u.Type(c.Name)
+		if out.Kind == Unknown {
+			out.Kind, out.Elem, out.Key, out.Len, out.ChanDir = c.Kind, c.Elem, c.Key, c.Len, c.ChanDir
+		}
+		return out
+	case Struct:
+		changed := false
+		members := make([]Member, 0, len(t.Members))
+		for _, m := range t.Members {
+			mt := s.typ(m.Type)
+			changed = changed || mt != m.Type
+			m.Type = mt
+			members = append(members, m)
+		}
+		if !changed {
+			return t
+		}
+		out := *t
+		out.Members = members
+		out.Name = Name{Name: anonymousName(&out)}
+		out.GoType = nil
+		return &out
+	case Func:
+		sig, changed := s.signature(t.Signature)
+		if !changed {
+			return t
+		}
+		out := *t
+		out.Signature = sig
+		out.Name = Name{Name: anonymousName(&out)}
+		out.GoType = nil
+		return &out
+	case Interface:
+		// The methods' receiver is the interface itself, which is not
+		// substituted, but replaced by the new interface.
+		changed := false
+		sigs := map[string]*Signature{}
+		for name, m := range t.Methods {
+			sig := *m.Signature
+			if sig.Receiver == t {
+				sig.Receiver = nil
+			}
+			var sigChanged bool
+			sigs[name], sigChanged = s.signature(&sig)
+			changed = changed || sigChanged
+		}
+		embeddeds, embeddedsChanged := s.types(t.EmbeddedInterfaces)
+		unions, unionsChanged := s.unions(t.Unions)
+		if !changed && !embeddedsChanged && !unionsChanged {
+			return t
+		}
+		out := &Type{}
+		*out = *t
+		out.Methods = map[string]*Type{}
+		for name, m := range t.Methods {
+			mt := *m
+			mt.Signature = sigs[name]
+			if m.Signature.Receiver == t {
+				mt.Signature.Receiver = out
+			}
+			mt.Name = Name{Name: "func (interface)." + name + strings.TrimPrefix(anonymousName(&mt), "func")}
+			mt.GoType = nil
+			out.Methods[name] = &mt
+		}
+		out.EmbeddedInterfaces = embeddeds
+		out.Unions = unions
+		out.Name = Name{Name: anonymousName(out)}
+		out.GoType = nil
+		return out
+	}
+	return t
+}
+
+// types replaces the type parameters in each of ts, and returns true if any
+// were changed.
+func (s substituter) types(ts []*Type) ([]*Type, bool) {
+	out := make([]*Type, 0, len(ts))
+	changed := false
+	for _, t := range ts {
+		st := s.typ(t)
+		changed = changed || st != t
+		out = append(out, st)
+	}
+	return out, changed
+}
+
+// unions replaces the type parameters in the terms of each of unions, and
+// returns true if any were changed.
+func (s substituter) unions(unions [][]UnionTerm) ([][]UnionTerm, bool) {
+	var out [][]UnionTerm
+	changed := false
+	for _, union := range unions {
+		terms := make([]UnionTerm, 0, len(union))
+		for _, term := range union {
+			st := s.typ(term.Type)
+			changed = changed || st != term.Type
+			terms = append(terms, UnionTerm{Tilde: term.Tilde, Type: st})
+		}
+		out = append(out, terms)
+	}
+	return out, changed
+}
+
+// signature replaces the type parameters in sig, and returns true if any
+// were changed. The type parameters which are replaced are removed from the
+// signature's own TypeParams.
+func (s substituter) signature(sig *Signature) (*Signature, bool) {
+	out := *sig
+	out.Receiver = s.typ(sig.Receiver)
+	changed := out.Receiver != sig.Receiver
+	out.TypeParams = nil
+	for _, tp := range sig.TypeParams {
+		if _, ok := s.args[tp]; ok {
+			changed = true
+			continue
+		}
+		out.TypeParams = append(out.TypeParams, tp)
+	}
+	params := func(in []*ParamResult) []*ParamResult {
+		var ps []*ParamResult
+		for _, p := range in {
+			pt := s.typ(p.Type)
+			changed = changed || pt != p.Type
+			ps = append(ps, &ParamResult{Name: p.Name, Type: pt})
+		}
+		return ps
+	}
+	out.Parameters = params(sig.Parameters)
+	out.Results = params(sig.Results)
+	return &out, changed
+}
+
+// mentionsTypeParams returns true if t refers to any type parameters.
+func mentionsTypeParams(t *Type) bool {
+	if t == nil {
+		return false
+	}
+	switch {
+	case t.Kind == TypeParam:
+		return true
+	case t.Origin != nil:
+		for _, arg := range t.TypeArgs {
+			if mentionsTypeParams(arg) {
+				return true
+			}
+		}
+		return false
+	case len(t.TypeParamList) != 0:
+		// A generic type referring to itself.
+		return true
+	case t.Name.Package != "":
+		return false
+	}
+
+	// Only anonymous types remain, which can't refer to themselves.
+	if mentionsTypeParams(t.Elem) || mentionsTypeParams(t.Key) {
+		return true
+	}
+	for _, m := range t.Members {
+		if mentionsTypeParams(m.Type) {
+			return true
+		}
+	}
+	if t.Kind == Interface {
+		for _, m := range t.Methods {
+			if mentionsTypeParams(m) {
+				return true
+			}
+		}
+		for _, e := range t.EmbeddedInterfaces {
+			if mentionsTypeParams(e) {
+				return true
+			}
+		}
+		for _, union := range t.Unions {
+			for _, term := range union {
+				if mentionsTypeParams(term.Type) {
+					return true
+				}
+			}
+		}
+	}
+	if sig := t.Signature; sig != nil {
+		for _, p := range append(slices.Clone(sig.Parameters), sig.Results...) {
+			if mentionsTypeParams(p.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// anonymousName returns the name of an anonymous type, in the same form as
+// go/types uses (and thus the parser).
+func anonymousName(t *Type) string {
+	switch t.Kind {
+	case Slice:
+		return "[]" + t.Elem.String()
+	case Array:
+		return "[" + strconv.FormatInt(t.Len, 10) + "]" + t.Elem.String()
+	case Pointer:
+		return "*" + t.Elem.String()
+	case Map:
+		return "map[" + t.Key.String() + "]" + t.Elem.String()
+	case Chan:
+		switch t.ChanDir {
+		case SendOnly:
+			return "chan<- " + t.Elem.String()
+		case RecvOnly:
+			return "<-chan " + t.Elem.String()
+		}
+		if t.Elem.Kind == Chan && t.Elem.Name.Package == "" && t.Elem.ChanDir == RecvOnly {
+			return "chan (" + t.Elem.String() + ")"
+		}
+		return "chan " + t.Elem.String()
+	case Struct:
+		fields := make([]string, 0, len(t.Members))
+		for _, m := range t.Members {
+			field := m.Type.String()
+			if !m.Embedded {
+				field = m.Name + " " + field
+			}
+			if m.Tags != "" {
+				field += " " + strconv.Quote(m.Tags)
+			}
+			fields = append(fields, field)
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}"
+	case Func:
+		sig := t.Signature
+		params := func(in []*ParamResult, variadic bool) []string {
+			out := make([]string, 0, len(in))
+			for i, p := range in {
+				pt := p.Type.String()
+				if variadic && i == len(in)-1 && p.Type.Kind == Slice {
+					pt = "..." + p.Type.Elem.String()
+				}
+				if p.Name != "" {
+					pt = p.Name + " " + pt
+				}
+				out = append(out, pt)
+			}
+			return out
+		}
+		name := "func(" + strings.Join(params(sig.Parameters, sig.Variadic), ", ") + ")"
+		results := params(sig.Results, false)
+		if len(results) == 1 && sig.Results[0].Name == "" {
+			name += " " + results[0]
+		} else if len(results) != 0 {
+			name += " (" + strings.Join(results, ", ") + ")"
+		}
+		return name
+	case Interface:
+		var elems []string
+		for _, name := range slices.Sorted(maps.Keys(t.Methods)) {
+			elems = append(elems, name+strings.TrimPrefix(anonymousName(t.Methods[name]), "func"))
+		}
+		for _, e := range t.EmbeddedInterfaces {
+			elems = append(elems, e.String())
+		}
+		for _, union := range t.Unions {
+			terms := make([]string, 0, len(union))
+			for _, term := range union {
+				terms = append(terms, term.String())
+			}
+			elems = append(elems, strings.Join(terms, " | "))
+		}
+		return "interface{" + strings.Join(elems, "; ") + "}"
+	}
+	return t.Name.Name
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestInstantiate(t *testing.T) {
+	u := Universe{}
+	pod := u.Type(Name{Package: "foo", Name: "Pod"})
+	pod.Kind = Struct
+
+	// type List[T any] struct {
+	//     Items []T
+	//     Next  *List[T]
+	// }
+	// func (l *List[T]) Get(i int) T
+	listT := &Type{Name: Name{Name: "T"}, Kind: TypeParam, Constraint: Any}
+	list := u.Type(Name{Package: "foo", Name: "List[T]"})
+	list.Kind = Struct
+	list.TypeParams = map[string]*Type{"T": Any}
+	list.TypeParamList = []*Type{listT}
+	list.Members = []Member{
+		{Name: "Items", Type: &Type{Name: Name{Name: "[]T"}, Kind: Slice, Elem: listT}},
+		{Name: "Next", Type: &Type{Name: Name{Name: "*foo.List[T]"}, Kind: Pointer, Elem: list}},
+	}
+	list.Methods = map[string]*Type{
+		"Get": {
+			Name: Name{Name: "func (*foo.List[T]).Get(i int) T"},
+			Kind: Func,
+			Signature: &Signature{
+				Receiver:   list.Members[1].Type,
+				Parameters: []*ParamResult{{Name: "i", Type: Int}},
+				Results:    []*ParamResult{{Type: listT}},
+				TypeParams: []*Type{listT},
+			},
+		},
+	}
+
+	// type Box[T any] struct {
+	//     List List[T]
+	//     Func func(T) string
+	// }
+	boxT := &Type{Name: Name{Name: "T"}, Kind: TypeParam, Constraint: Any}
+	box := u.Type(Name{Package: "foo", Name: "Box[T]"})
+	box.Kind = Struct
+	box.TypeParamList = []*Type{boxT}
+	box.Members = []Member{
+		{Name: "List", Type: &Type{Name: Name{Package: "foo", Name: "List[T]"}, Kind: Struct, Origin: list, TypeArgs: []*Type{boxT}}},
+		{Name: "Func", Type: &Type{
+			Name:      Name{Name: "func(T) string"},
+			Kind:      Func,
+			Signature: &Signature{Parameters: []*ParamResult{{Type: boxT}}, Results: []*ParamResult{{Type: String}}},
+		}},
+	}
+
+	// The parser has already seen []foo.Pod.
+	podSlice := u.Type(Name{Name: "[]foo.Pod"})
+	podSlice.Kind = Slice
+	podSlice.Elem = pod
+
+	listOfPods, err := u.Instantiate(list, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := (Name{Package: "foo", Name: "List[foo.Pod]"}), listOfPods.Name; want != got {
+		t.Errorf("wrong name: want %v, got %v", want, got)
+	}
+	if listOfPods.Kind != Struct || listOfPods.Origin != list || len(listOfPods.TypeArgs) != 1 || listOfPods.TypeArgs[0] != pod {
+		t.Errorf("expected an instance of List with Pod, got %#v", listOfPods)
+	}
+	if listOfPods.TypeParamList != nil {
+		t.Errorf("expected no type params, got %v", listOfPods.TypeParamList)
+	}
+	if items := listOfPods.Members[0].Type; items != podSlice {
+		t.Errorf("expected Items to be the existing []foo.Pod, got %#v", items)
+	}
+	next := listOfPods.Members[1].Type
+	if next.Kind != Pointer || next.Elem != listOfPods || next.Name.Name != "*foo.List[foo.Pod]" {
+		t.Errorf("expected Next to be *List[Pod], got %#v", next)
+	}
+	get := listOfPods.Methods["Get"]
+	if get == nil {
+		t.Fatalf("expected method Get")
+	}
+	if want, got := "func (*foo.List[foo.Pod]).Get(i int) foo.Pod", get.Name.Name; want != got {
+		t.Errorf("wrong method name: want %q, got %q", want, got)
+	}
+	if sig := get.Signature; sig.Receiver != next || sig.Parameters[0].Type != Int || sig.Results[0].Type != pod || sig.TypeParams != nil {
+		t.Errorf("wrong method signature: %#v", sig)
+	}
+	if list.Methods["Get"].Signature.Results[0].Type != listT {
+		t.Errorf("the generic type's method was changed")
+	}
+
+	// Instances are shared.
+	if again, err := u.Instantiate(list, pod); err != nil || again != listOfPods {
+		t.Errorf("expected the same instance, got %p (%v), want %p", again, err, listOfPods)
+	}
+	if u.Instance(list, pod) != listOfPods {
+		t.Errorf("expected the instance to be kept in the universe")
+	}
+	if u.Package("foo").Instances["List[foo.Pod]"] != listOfPods {
+		t.Errorf("expected the instance to be kept in its package")
+	}
+
+	boxOfPods, err := u.Instantiate(box, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if boxOfPods.Members[0].Type != listOfPods {
+		t.Errorf("expected Box[Pod].List to be List[Pod], got %#v", boxOfPods.Members[0].Type)
+	}
+	fn := boxOfPods.Members[1].Type
+	if want, got := "func(foo.Pod) string", fn.Name.Name; want != got {
+		t.Errorf("wrong func name: want %q, got %q", want, got)
+	}
+	if fn.Signature.Parameters[0].Type != pod || fn.Signature.Results[0].Type != String {
+		t.Errorf("wrong func signature: %#v", fn.Signature)
+	}
+
+	// Instantiating with a type parameter of another generic type.
+	listOfBoxT, err := u.Instantiate(list, boxT)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if listOfBoxT.Members[0].Type.Elem != boxT {
+		t.Errorf("expected List[T].Items to be []T of Box's T")
+	}
+	// Which can't be told apart by name from List[T] with another T.
+	listOfListT, err := u.Instantiate(list, listT)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if listOfListT == listOfBoxT || listOfListT.Members[0].Type.Elem != listT {
+		t.Errorf("expected List[T].Items to be []T of List's T")
+	}
+
+	// type Slice[T any] interface {
+	//     ~[]T
+	// }
+	sliceT := &Type{Name: Name{Name: "T"}, Kind: TypeParam, Constraint: Any}
+	slice := u.Type(Name{Package: "foo", Name: "Slice[T]"})
+	slice.Kind = Interface
+	slice.TypeParamList = []*Type{sliceT}
+	slice.Unions = [][]UnionTerm{{{Tilde: true, Type: &Type{Name: Name{Name: "[]T"}, Kind: Slice, Elem: sliceT}}}}
+	sliceOfPods, err := u.Instantiate(slice, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if terms := sliceOfPods.Unions; len(terms) != 1 || len(terms[0]) != 1 || !terms[0][0].Tilde || terms[0][0].Type != podSlice {
+		t.Errorf("expected Slice[Pod] to be ~[]foo.Pod, got %v", terms)
+	}
+
+	// type Getter[T any] struct {
+	//     G interface{ Get() T }
+	// }
+	getterT := &Type{Name: Name{Name: "T"}, Kind: TypeParam, Constraint: Any}
+	iface := &Type{Name: Name{Name: "interface{Get() T}"}, Kind: Interface}
+	iface.Methods = map[string]*Type{
+		"Get": {
+			Name:      Name{Name: "func (interface).Get() T"},
+			Kind:      Func,
+			Signature: &Signature{Receiver: iface, Results: []*ParamResult{{Type: getterT}}},
+		},
+	}
+	getter := u.Type(Name{Package: "foo", Name: "Getter[T]"})
+	getter.Kind = Struct
+	getter.TypeParamList = []*Type{getterT}
+	getter.Members = []Member{{Name: "G", Type: iface}}
+	getterOfPods, err := u.Instantiate(getter, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := getterOfPods.Members[0].Type
+	if want, got := "interface{Get() foo.Pod}", g.Name.Name; want != got {
+		t.Errorf("wrong interface name: want %q, got %q", want, got)
+	}
+	get = g.Methods["Get"]
+	if want, got := "func (interface).Get() foo.Pod", get.Name.Name; want != got {
+		t.Errorf("wrong method name: want %q, got %q", want, got)
+	}
+	if sig := get.Signature; sig.Receiver != g || sig.Results[0].Type != pod {
+		t.Errorf("wrong method signature: %#v", sig)
+	}
+	if iface.Methods["Get"].Signature.Receiver != iface {
+		t.Errorf("the generic type's interface was changed")
+	}
+
+	for _, tc := range []struct {
+		name    string
+		generic *Type
+		args    []*Type
+	}{
+		{"not generic", pod, []*Type{Int}},
+		{"too few args", list, nil},
+		{"too many args", list, []*Type{Int, Int}},
+		{"instance", listOfPods, []*Type{Int}},
+	} {
+		if _, err := u.Instantiate(tc.generic, tc.args...); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package types
 
 import (
+	"go/token"
 	gotypes "go/types"
+	"slices"
 	"strings"
 )
 
@@ -107,6 +109,31 @@ const (
 	Protobuf Kind = "Protobuf"
 )
 
+// ChanDir is the direction of a channel type.
+type ChanDir string
+
+const (
+	// SendRecv is a bidirectional channel, e.g. chan T.
+	SendRecv ChanDir = ""
+	// SendOnly is a channel which can only be sent to, e.g. chan<- T.
+	SendOnly ChanDir = "SendOnly"
+	// RecvOnly is a channel which can only be received from, e.g. <-chan T.
+	RecvOnly ChanDir = "RecvOnly"
+)
+
+// ReceiverKind is the kind of receiver a method is declared with.
+type ReceiverKind string
+
+const (
+	// ValueReceiver is a method declared on a type, e.g. func (t T) M().
+	ValueReceiver ReceiverKind = "Value"
+	// PointerReceiver is a method declared on a pointer to a type, e.g.
+	// func (t *T) M().
+	PointerReceiver ReceiverKind = "Pointer"
+	// InterfaceReceiver is a method of an interface.
+	InterfaceReceiver ReceiverKind = "Interface"
+)
+
 // Package holds package-level information.
 // Fields are public, as everything in this package, to enable consumption by
 // templates (for example). But it is strongly encouraged for code to build by
@@ -118,6 +145,16 @@ type Package struct {
 	// The location (on disk) of this package.
 	Dir string
 
+	// The Go source files which make up this package, as selected by the
+	// build (e.g. subject to build tags), with absolute paths.
+	GoFiles []string
+
+	// The _test.go files of this package, with absolute paths, if they were
+	// loaded (see parser.Options.IncludeTestFiles). These are not included
+	// in GoFiles. All of the files of an external test package (e.g.
+	// "package foo_test") are test files.
+	TestGoFiles []string
+
 	// Short name of this package, as in the 'package x' line.
 	Name string
 
@@ -144,6 +181,10 @@ type Package struct {
 	// package name).
 	Constants map[string]*Type
 
+	// Instances of this package's generic types, e.g. Foo[int], indexed by
+	// their name (*not* including package name). See Universe.Instance.
+	Instances map[string]*Type
+
 	// Packages imported by this package, indexed by (canonicalized)
 	// package path.
 	Imports map[string]*Package
@@ -283,6 +324,7 @@ func (u Universe) Package(packagePath string) *Package {
 		Functions: map[string]*Type{},
 		Variables: map[string]*Type{},
 		Constants: map[string]*Type{},
+		Instances: map[string]*Type{},
 		Imports:   map[string]*Package{},
 	}
 	u[packagePath] = p
@@ -304,10 +346,35 @@ type Type struct {
 	// The general kind of this type.
 	Kind Kind
 
+	// Where this type, or the declaration it describes, is defined in the
+	// source. This is the zero Position (see Position.IsValid) for types
+	// which have no declaration, such as builtin and anonymous types.
+	Position token.Position
+
+	// If this is a named type, function, variable, or constant which a
+	// package declares, its declarations are numbered from 1 in the order
+	// in which they appear in the package's files (see Package.GoFiles),
+	// and this is the number of this one. It is 0 for other types.
+	DeclarationOrder int
+
 	// If there are comment lines immediately before the type definition,
 	// they will be recorded here.
 	CommentLines []string
 
+	// The position of each of CommentLines, i.e. of the comment marker
+	// which starts each line (or for lines inside a /* */ comment, of the
+	// line itself).
+	CommentLinePositions []token.Position
+
+	// If this is a constant, a variable, or an interface's method, and
+	// there is a comment after its declaration on the same line, e.g.
+	//	Foo = 1 // +marker
+	// its lines are recorded here.
+	TrailingCommentLines []string
+
+	// The position of each of TrailingCommentLines.
+	TrailingCommentLinePositions []token.Position
+
 	// If there are comment lines preceding the `CommentLines`, they will be
 	// recorded here. There are two cases:
 	// ---
@@ -325,12 +392,34 @@ type Type struct {
 	// ---
 	SecondClosestCommentLines []string
 
+	// The position of each of SecondClosestCommentLines.
+	SecondClosestCommentLinePositions []token.Position
+
 	// If Kind == Struct
 	Members []Member
 
-	// If Kind == Struct
+	// If this is a generic type, the constraints of its type parameters,
+	// keyed by the type parameters' names. (This is always set for named
+	// structs and interfaces, even if they are not generic.)
 	TypeParams map[string]*Type
 
+	// If this is a generic type, its type parameters, in the order in which
+	// they are declared. Each has Kind == TypeParam.
+	TypeParamList []*Type
+
+	// If Kind == TypeParam, this is the type parameter's constraint, which
+	// is an interface.
+	Constraint *Type
+
+	// If this is an instance of a generic type (e.g. Foo[int]), this is the
+	// generic type (e.g. Foo[T]), and TypeArgs are the type arguments, in
+	// order. Members, Elem, etc. describe the instance, with the type
+	// arguments in place of the type parameters. The methods of an instance
+	// are only recorded once it is passed to Universe.Instantiate; until
+	// then, see those of the Origin.
+	Origin   *Type
+	TypeArgs []*Type
+
 	// If Kind == Map, Slice, Pointer, or Chan
 	Elem *Type
 
@@ -343,9 +432,29 @@ type Type struct {
 
 	// If Kind == Interface, this is the set of all required functions.
 	// Otherwise, if this is a named type, this is the list of methods that
-	// type has. (All elements will have Kind=="Func")
+	// type has. (All elements will have Kind=="Func", and their Position
+	// and CommentLines describe the method's declaration.)
 	Methods map[string]*Type
 
+	// The method set of this type, i.e. the methods which can be called on
+	// a value of it, including those promoted from embedded fields, sorted
+	// by name. PointerMethodSet is the method set of a pointer to this
+	// type, which also includes the methods with pointer receivers. These
+	// are only recorded for the named types declared by the packages which
+	// were parsed; see LookupMethod.
+	MethodSet        []*MethodSelection
+	PointerMethodSet []*MethodSelection
+
+	// If Kind == Interface, the interfaces embedded in it, in the order in
+	// which they are declared. Their methods are also included in Methods.
+	EmbeddedInterfaces []*Type
+
+	// If Kind == Interface, its type elements, e.g. ~int | ~string, in the
+	// order in which they are declared. Each is the union of its terms, and
+	// the interface's type set is the intersection of all of them. A single
+	// embedded non-interface type, e.g. int, is a union of one term.
+	Unions [][]UnionTerm
+
 	// If Kind == func, this is the signature of the function.
 	Signature *Signature
 
@@ -356,16 +465,55 @@ type Type struct {
 	// a human-readable literal.
 	ConstValue *string
 
-	// TODO: Add:
-	// * channel direction
+	// If this is a constant, where it is declared within its const
+	// declaration. See Package.Enums.
+	ConstDecl *ConstDecl
+
+	// If Kind == Chan, the direction in which values can be passed through
+	// the channel.
+	ChanDir ChanDir
 
 	// If Kind == Array
 	Len int64
 
-	// The underlying Go type.
+	// The underlying Go type. This is nil if the Universe was loaded from a
+	// cache (see parser.Options.CacheDir).
 	GoType gotypes.Type
 }
 
+// UnionTerm is one of the terms of a union in an interface, e.g. ~int in
+// ~int | ~string.
+type UnionTerm struct {
+	// If true, this is an approximation element (~Type), which includes
+	// all types whose underlying type is Type.
+	Tilde bool
+	Type  *Type
+}
+
+// String returns the term as it is written in Go.
+func (t UnionTerm) String() string {
+	if t.Tilde {
+		return "~" + t.Type.String()
+	}
+	return t.Type.String()
+}
+
+// ConstDecl describes where a constant is declared within its const
+// declaration, which may be a block of several constants.
+type ConstDecl struct {
+	// The position of the const declaration, i.e. of the "const" keyword,
+	// which identifies the block which the constant is declared in.
+	Block token.Position
+
+	// The index of the constant's line (its ValueSpec) within the
+	// declaration, which is the value of iota for it.
+	Iota int
+
+	// True if the constant's value is computed with iota, either by its own
+	// expression, or by the one which it implicitly repeats.
+	UsesIota bool
+}
+
 // String returns the name of the type.
 func (t *Type) String() string {
 	if t == nil {
@@ -410,7 +558,167 @@ func (t *Type) IsAnonymousStruct() bool {
 
 // IsComparable returns whether the type is comparable.
 func (t *Type) IsComparable() bool {
-	return gotypes.Comparable(t.GoType)
+	if t.GoType != nil {
+		return gotypes.Comparable(t.GoType)
+	}
+	switch t.Kind {
+	case Builtin, Pointer, Chan, Interface:
+		return true
+	case Alias:
+		return t.Underlying.IsComparable()
+	case Array:
+		return t.Elem.IsComparable()
+	case Struct:
+		for _, m := range t.Members {
+			if !m.Type.IsComparable() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// LookupMethod returns the method called name in the method set of t, or of
+// *t if pointer is true, or nil if there is no such method.
+func (t *Type) LookupMethod(name string, pointer bool) *MethodSelection {
+	methods := t.MethodSet
+	if pointer {
+		methods = t.PointerMethodSet
+	}
+	i, found := slices.BinarySearchFunc(methods, name, func(m *MethodSelection, name string) int {
+		return strings.Compare(m.Name, name)
+	})
+	if !found {
+		return nil
+	}
+	return methods[i]
+}
+
+// MethodSelection is a method in the method set of a type.
+type MethodSelection struct {
+	// The name of the method.
+	Name string
+
+	// The method, as recorded in the Methods of the type which declares it.
+	// For methods of generic types (and their instances), this is the
+	// method of the generic type.
+	Method *Type
+
+	// The kind of receiver the method is declared with.
+	ReceiverKind ReceiverKind
+
+	// If the method is promoted from an embedded field, the names of the
+	// embedded fields it is promoted through, outermost first. This is empty
+	// for the type's own methods.
+	Path []string
+}
+
+// FieldSelection is a field which can be selected on a struct.
+type FieldSelection struct {
+	// The field, as it is declared in the struct which declares it.
+	Member Member
+
+	// If the field is promoted from an embedded field, the names of the
+	// embedded fields it is promoted through, outermost first. This is empty
+	// for the struct's own fields.
+	Path []string
+}
+
+// FieldSet returns the fields which can be selected on a value of t: its
+// own fields, and the fields promoted from its embedded fields. As in Go, a
+// field (or method) shadows those with the same name which are embedded more
+// deeply, and if there are several at the same depth, the name is ambiguous
+// and none of them are included. The fields are ordered by depth, and then
+// in the order in which they are declared. If t is not a struct, this
+// returns nil.
+func (t *Type) FieldSet() []*FieldSelection {
+	type embedded struct {
+		t    *Type
+		path []string
+		// True if the same type is embedded more than once at this depth.
+		multiple bool
+	}
+
+	var out []*FieldSelection
+	// The names which are resolved at a shallower depth.
+	resolved := map[string]bool{}
+	// The types which were embedded at a shallower depth.
+	seen := map[*Type]bool{}
+	current := []embedded{{t: t}}
+	for len(current) != 0 {
+		var next []embedded
+		var names []string
+		found := map[string]*FieldSelection{}
+		count := map[string]int{}
+		add := func(name string, fs *FieldSelection, multiple bool) {
+			if count[name] == 0 {
+				names = append(names, name)
+			}
+			count[name]++
+			if multiple {
+				count[name]++
+			}
+			found[name] = fs
+		}
+		for _, e := range current {
+			typ := e.t
+			if typ.Kind == Pointer {
+				typ = typ.Elem
+			}
+			if seen[typ] {
+				continue
+			}
+			seen[typ] = true
+
+			methods := typ.Methods
+			if methods == nil && typ.Origin != nil {
+				methods = typ.Origin.Methods
+			}
+			for name := range methods {
+				add(name, nil, e.multiple)
+			}
+			underlying := typ
+			for underlying.Kind == Alias && underlying.Underlying != nil {
+				underlying = underlying.Underlying
+			}
+			if underlying.Kind != Struct {
+				continue
+			}
+			for _, m := range underlying.Members {
+				add(m.Name, &FieldSelection{Member: m, Path: e.path}, e.multiple)
+				if m.Embedded {
+					next = append(next, embedded{t: m.Type, path: append(slices.Clone(e.path), m.Name)})
+				}
+			}
+		}
+		for _, name := range names {
+			if resolved[name] {
+				continue
+			}
+			resolved[name] = true
+			if fs := found[name]; fs != nil && count[name] == 1 {
+				out = append(out, fs)
+			}
+		}
+		// If the same type is embedded more than once at the same depth,
+		// all of its fields are ambiguous.
+		current = nil
+		index := map[*Type]int{}
+		for _, e := range next {
+			typ := e.t
+			if typ.Kind == Pointer {
+				typ = typ.Elem
+			}
+			if i, ok := index[typ]; ok {
+				current[i].multiple = true
+				continue
+			}
+			index[typ] = len(current)
+			current = append(current, e)
+		}
+	}
+	return out
 }
 
 // A single struct member
@@ -422,10 +730,24 @@ type Member struct {
 	// Name will be the type name.
 	Embedded bool
 
+	// Where the member is declared in the source.
+	Position token.Position
+
 	// If there are comment lines immediately before the member in the type
 	// definition, they will be recorded here.
 	CommentLines []string
 
+	// The position of each of CommentLines.
+	CommentLinePositions []token.Position
+
+	// If there is a comment after the member on the same line, e.g.
+	//	Foo int `json:"foo"` // +optional
+	// its lines are recorded here.
+	TrailingCommentLines []string
+
+	// The position of each of TrailingCommentLines.
+	TrailingCommentLinePositions []token.Position
+
 	// If there are tags along with this member, they will be saved here.
 	Tags string
 
@@ -456,6 +778,11 @@ type Signature struct {
 	// True if the last in parameter is of the form ...T.
 	Variadic bool
 
+	// If this is a generic function, its type parameters, in order. If this
+	// is a method of a generic type, the type parameters of the receiver,
+	// which are those of the generic type.
+	TypeParams []*Type
+
 	// If there are comment lines immediately before this
 	// signature/method/function declaration, they will be recorded here.
 	CommentLines []string
@@ -17,6 +17,9 @@ limitations under the License.
 package types
 
 import (
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -208,3 +211,49 @@ func Test_Type_IsAssignable(t *testing.T) {
 		}
 	}
 }
+
+func TestFieldSet(t *testing.T) {
+	field := func(name string, typ *Type) Member {
+		return Member{Name: name, Type: typ}
+	}
+	embed := func(typ *Type) Member {
+		name := typ.Name.Name
+		if typ.Kind == Pointer {
+			name = typ.Elem.Name.Name
+		}
+		return Member{Name: name, Embedded: true, Type: typ}
+	}
+	named := func(name string, members ...Member) *Type {
+		return &Type{Name: Name{Package: "pkg", Name: name}, Kind: Struct, Members: members}
+	}
+
+	// type E struct { Dup int }
+	// type A struct { X, Y int; E }
+	// type B struct { Y, Z int; E }
+	// func (B) W()
+	// type C struct { W, Deep int }
+	// type D struct { C }
+	// type Outer struct { A; *B; X string; D }
+	e := named("E", field("Dup", Int))
+	a := named("A", field("X", Int), field("Y", Int), embed(e))
+	b := named("B", field("Y", Int), field("Z", Int), embed(e))
+	b.Methods = map[string]*Type{"W": {Name: Name{Name: "func (pkg.B).W()"}, Kind: Func}}
+	c := named("C", field("W", Int), field("Deep", Int))
+	d := named("D", embed(c))
+	outer := named("Outer", embed(a), embed(&Type{Name: Name{Name: "*pkg.B"}, Kind: Pointer, Elem: b}), field("X", String), embed(d))
+
+	var got []string
+	for _, fs := range outer.FieldSet() {
+		got = append(got, strings.Join(append(slices.Clone(fs.Path), fs.Member.Name), "."))
+	}
+	// A.X is shadowed by X, A.Y and B.Y are ambiguous, as are A.E and B.E
+	// and so E.Dup, and C.W is shadowed by B.W.
+	expected := []string{"A", "B", "X", "D", "B.Z", "D.C", "D.C.Deep"}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("wrong field set: want %q, got %q", expected, got)
+	}
+
+	if fs := String.FieldSet(); fs != nil {
+		t.Errorf("expected no fields for a builtin, got %v", fs)
+	}
+}